@@ -0,0 +1,93 @@
+package broadcaster
+
+import (
+	"sync"
+	"time"
+)
+
+func ListenBatches[T any](b Broadcaster[T], size int, maxDelay time.Duration, opts ...ListenerOption) (<-chan []T, CancelFunc, error) {
+	// batches without any limit would only be sent when the listener is closed
+	if size <= 0 && maxDelay <= 0 {
+		return nil, nil, ErrUnboundedBatch
+	}
+	input, cancel, err := b.Listen(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batches := make(chan []T)
+	stop := make(chan struct{})
+	go batch(input, batches, stop, size, maxDelay)
+
+	var once sync.Once
+	return batches, func() {
+		once.Do(func() {
+			close(stop)
+			cancel()
+		})
+	}, nil
+}
+
+func batch[T any](input <-chan T, batches chan<- []T, stop <-chan struct{}, size int, maxDelay time.Duration) {
+	defer close(batches)
+	defer func() {
+		// keep receiving so the broadcaster isn't blocked until the listener is closed
+		for range input {
+		}
+	}()
+
+	var pending []T
+	var timer *time.Timer
+	var deadline <-chan time.Time
+
+	flush := func() bool {
+		if timer != nil {
+			stopTimer(timer)
+		}
+		deadline = nil
+		select {
+		case batches <- pending:
+			pending = nil
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	for {
+		select {
+		case val, ok := <-input:
+			if !ok {
+				if len(pending) > 0 {
+					flush()
+				}
+				return
+			}
+			pending = append(pending, val)
+			if size > 0 && len(pending) >= size {
+				if !flush() {
+					return
+				}
+				continue
+			}
+			// the delay is counted from the first object of the batch
+			if len(pending) == 1 && maxDelay > 0 {
+				if timer == nil {
+					timer = time.NewTimer(maxDelay)
+				} else {
+					timer.Reset(maxDelay)
+				}
+				deadline = timer.C
+			}
+
+		case <-deadline:
+			deadline = nil
+			if !flush() {
+				return
+			}
+
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package broadcaster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestListenBatches(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	batches, cancel, err := ListenBatches(b, 3, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	defer cancel()
+
+	for i := 1; i <= 4; i++ {
+		ch <- i
+	}
+	if batch := <-batches; !reflect.DeepEqual(batch, []int{1, 2, 3}) {
+		t.Errorf("expected full batch [1 2 3], got %v", batch)
+	}
+
+	start := time.Now()
+	if batch := <-batches; !reflect.DeepEqual(batch, []int{4}) {
+		t.Errorf("expected delayed batch [4], got %v", batch)
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("expected batch to be delayed, got it after %v", elapsed)
+	}
+
+	ch <- 5
+	close(ch)
+	if batch := <-batches; !reflect.DeepEqual(batch, []int{5}) {
+		t.Errorf("expected remaining batch [5], got %v", batch)
+	}
+	if _, ok := <-batches; ok {
+		t.Error("expected batches to be closed")
+	}
+}
+
+func TestListenBatchesCancel(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	batches, cancel, err := ListenBatches(b, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1 // pending batch is never received
+	cancel()
+	cancel()
+
+	for range batches {
+	}
+	if stats := b.Stats(); stats.Listeners != 0 {
+		t.Errorf("expected no listeners, got %d", stats.Listeners)
+	}
+}
+
+func TestListenBatchesUnbounded(t *testing.T) {
+	ch := make(chan int)
+	defer close(ch)
+	b := NewBroadcaster(ch)
+
+	if _, _, err := ListenBatches(b, 0, 0); err != ErrUnboundedBatch {
+		t.Errorf("expected err == ErrUnboundedBatch, got '%v'", err)
+	}
+	if stats := b.Stats(); stats.Listeners != 0 {
+		t.Errorf("expected no listeners, got %d", stats.Listeners)
+	}
+}
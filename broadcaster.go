@@ -1,12 +1,31 @@
 package broadcaster
 
 import (
+	"context"
 	"errors"
+	"hash/maphash"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var ErrBroadcasterClosed = errors.New("broadcaster is closed")
+var (
+	ErrBroadcasterClosed = errors.New("broadcaster is closed")
+	ErrOptionType        = errors.New("listener option does not match broadcaster type")
+	ErrListenerTimeout   = errors.New("listener timed out")
+	ErrIdleTimeout       = errors.New("broadcaster was idle for too long")
+	ErrListenerKicked    = errors.New("listener was kicked")
+	ErrUnboundedBatch    = errors.New("batch size or max delay has to be positive")
+	ErrSharedStore       = errors.New("store cannot be shared by multiple broadcasters")
+)
+
+const drainInterval = 10 * time.Millisecond
+
+var expired = make(chan time.Time)
+
+func init() {
+	close(expired)
+}
 
 type CancelFunc func()
 
@@ -14,24 +33,73 @@ type Converter[In, Out any] func(In) (Out, bool)
 
 type Broadcaster[T any] interface {
 	Listen(opts ...ListenerOption) (<-chan T, CancelFunc, error)
+	ListenHandle(opts ...ListenerOption) (*ListenerHandle[T], error)
+	ListenEnvelopes(opts ...ListenerOption) (<-chan Envelope[T], CancelFunc, error)
 	IsClosed() bool
 	Done() <-chan struct{}
+	Latest() (T, bool)
+	Listeners() []ListenerInfo
+	Kick(id string, reason error) bool
+	WaitForListeners(ctx context.Context, n int) error
+	Shutdown(ctx context.Context) error
+	Stats() Stats
+}
+
+type Stats struct {
+	Listeners int
+	Received  uint64
+	Delivered uint64
+	Dropped   uint64
+	Rejected  uint64
+	TimedOut  uint64
+	Buffers   []BufferStats
+}
+
+type BufferStats struct {
+	Len int
+	Cap int
 }
 
 type broadcaster[In, Out any] struct {
 	broadcasterOptions
 	input     <-chan In
 	convert   Converter[In, Out]
-	listeners map[chan<- Out]listenerOptions
+	target    func(In) Target
+	store     Store[Out]
+	storeErr  error
+	listeners map[*listener[Out]]struct{}
+	groups    map[string]*group[Out]
+	seed      maphash.Seed
+	mu        sync.RWMutex
+	counters  counters
+	seq       uint64
+	history   []Envelope[Out]
+	slow      []*listener[Out]
+	timer     *time.Timer
+	lastOut   atomic.Pointer[Out]
+	joined    chan struct{}
 	reg       chan listenerRequest[Out]
 	unreg     chan listenerRequest[Out]
+	due       chan *listener[Out]
+	caughtUp  chan *listener[Out]
 	closed    chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	stopCtx   context.Context
+}
+
+type counters struct {
+	received  atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	rejected  atomic.Uint64
+	timedOut  atomic.Uint64
 }
 
 type listenerRequest[T any] struct {
-	channel chan<- T
-	opts    listenerOptions
-	done    chan struct{}
+	listener *listener[T]
+	err      error
+	done     chan struct{}
 }
 
 func NewBroadcaster[T any](input <-chan T, opts ...BroadcasterOption) Broadcaster[T] {
@@ -43,40 +111,89 @@ func NewConverterBroadcaster[In, Out any](input <-chan In, convert Converter[In,
 		broadcasterOptions: defaultBroadcasterOptions,
 		input:              input,
 		convert:            convert,
-		listeners:          make(map[chan<- Out]listenerOptions),
+		listeners:          make(map[*listener[Out]]struct{}),
+		groups:             make(map[string]*group[Out]),
+		seed:               maphash.MakeSeed(),
 		reg:                make(chan listenerRequest[Out]),
 		unreg:              make(chan listenerRequest[Out]),
+		due:                make(chan *listener[Out]),
+		caughtUp:           make(chan *listener[Out]),
+		joined:             make(chan struct{}),
 		closed:             make(chan struct{}),
+		stop:               make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(&b.broadcasterOptions)
 	}
+	if b.latest && b.replay < 1 {
+		b.replay = 1
+	}
+	if b.blocking && b.minLis < 1 {
+		b.minLis = 1
+	}
+	if target, ok := b.route.(func(In) Target); ok {
+		b.target = target
+	}
+	if b.storage != nil {
+		if store, ok := b.storage.(Store[Out]); ok {
+			b.store = store
+			b.seq = store.LastSeq()
+		} else {
+			b.storeErr = ErrOptionType
+		}
+	}
 	go b.run()
 	return b
 }
 
 func (b *broadcaster[In, Out]) Listen(opts ...ListenerOption) (<-chan Out, CancelFunc, error) {
-	lisOpts := listenerOptions{
-		bufSize: b.lisBufSize,
-	}
-	for _, opt := range opts {
-		opt(&lisOpts)
+	h, err := b.ListenHandle(opts...)
+	if err != nil {
+		return nil, nil, err
 	}
+	return h.C, h.Cancel, nil
+}
+
+func (b *broadcaster[In, Out]) ListenHandle(opts ...ListenerOption) (*ListenerHandle[Out], error) {
+	return listenHandle(b.listen, opts)
+}
 
-	listener := make(chan Out, lisOpts.bufSize)
-	if err := b.register(listener, lisOpts); err != nil {
+func (b *broadcaster[In, Out]) ListenEnvelopes(opts ...ListenerOption) (<-chan Envelope[Out], CancelFunc, error) {
+	return listenEnvelopes(b.listen, opts)
+}
+
+func (b *broadcaster[In, Out]) listen(opts []ListenerOption, newOutlet func(bufSize int) outlet[Out]) (*listener[Out], CancelFunc, error) {
+	l, lisOpts, err := newListener[Out](&b.broadcasterOptions, opts)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	cancel := sync.OnceFunc(func() { b.unregister(listener) })
+	// replayed messages have to fit in the buffer
+	l.attach(newOutlet(max(lisOpts.bufSize, b.replay)))
+	l.wake = func() {
+		select {
+		case b.due <- l:
+		case <-b.closed:
+		}
+	}
+	var once sync.Once
+	l.cancelled = make(chan struct{})
+	l.cancel = func(err error) {
+		once.Do(func() {
+			// a blocked send to this listener shouldn't hold back the unregistration
+			close(l.cancelled)
+			b.unregister(l, err)
+		})
+	}
+	if err := b.register(l); err != nil {
+		l.out.close()
+		return nil, nil, err
+	}
 	if lisOpts.ctx != nil {
-		go func() {
-			<-lisOpts.ctx.Done()
-			cancel()
-		}()
+		go l.cancelOnDone(lisOpts.ctx, l.cancel)
 	}
 
-	return listener, cancel, nil
+	return l, func() { l.cancel(context.Canceled) }, nil
 }
 
 func (b *broadcaster[In, Out]) IsClosed() bool {
@@ -92,25 +209,132 @@ func (b *broadcaster[In, Out]) Done() <-chan struct{} {
 	return b.closed
 }
 
-func (b *broadcaster[In, Out]) register(listener chan<- Out, opts listenerOptions) error {
+func (b *broadcaster[In, Out]) Latest() (Out, bool) {
+	if latest := b.lastOut.Load(); latest != nil {
+		return *latest, true
+	}
+	var zero Out
+	return zero, false
+}
+
+func (b *broadcaster[In, Out]) Listeners() []ListenerInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	infos := make([]ListenerInfo, 0, len(b.listeners))
+	for l := range b.listeners {
+		infos = append(infos, l.info())
+	}
+	return infos
+}
+
+func (b *broadcaster[In, Out]) Kick(id string, reason error) bool {
+	if len(id) == 0 {
+		return false
+	}
+	if reason == nil {
+		reason = ErrListenerKicked
+	}
+
+	var kicked []*listener[Out]
+	b.mu.RLock()
+	for l := range b.listeners {
+		if l.id == id {
+			kicked = append(kicked, l)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, l := range kicked {
+		l.cancel(reason)
+	}
+	return len(kicked) > 0
+}
+
+func (b *broadcaster[In, Out]) WaitForListeners(ctx context.Context, n int) error {
+	for {
+		b.mu.RLock()
+		count, joined := len(b.listeners), b.joined
+		b.mu.RUnlock()
+		if count >= n {
+			return nil
+		}
+
+		select {
+		case <-joined:
+		case <-b.closed:
+			return ErrBroadcasterClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *broadcaster[In, Out]) Shutdown(ctx context.Context) error {
+	b.stopOnce.Do(func() {
+		b.stopCtx = ctx
+		close(b.stop)
+	})
+	select {
+	case <-b.closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *broadcaster[In, Out]) Stats() Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := Stats{
+		Listeners: len(b.listeners),
+		Received:  b.counters.received.Load(),
+		Delivered: b.counters.delivered.Load(),
+		Dropped:   b.counters.dropped.Load(),
+		Rejected:  b.counters.rejected.Load(),
+		TimedOut:  b.counters.timedOut.Load(),
+		Buffers:   make([]BufferStats, 0, len(b.listeners)),
+	}
+	for l := range b.listeners {
+		stats.Buffers = append(stats.Buffers, BufferStats{
+			Len: l.out.len(),
+			Cap: l.out.cap(),
+		})
+	}
+	return stats
+}
+
+func (b *broadcaster[In, Out]) register(l *listener[Out]) error {
 	req := listenerRequest[Out]{
-		channel: listener,
-		opts:    opts,
-		done:    make(chan struct{}),
+		listener: l,
+		done:     make(chan struct{}),
+	}
+	// the broadcaster was closed right away because of the store
+	if b.storeErr != nil {
+		return b.storeErr
+	}
+	select {
+	case <-b.stop:
+		return ErrBroadcasterClosed
+	default:
 	}
 	select {
 	case <-b.closed:
 		return ErrBroadcasterClosed
+	case <-b.stop:
+		return ErrBroadcasterClosed
 	case b.reg <- req:
 		<-req.done
 		return nil
 	}
 }
 
-func (b *broadcaster[In, Out]) unregister(listener chan<- Out) {
+func (b *broadcaster[In, Out]) unregister(l *listener[Out], err error) {
 	req := listenerRequest[Out]{
-		channel: listener,
-		done:    make(chan struct{}),
+		listener: l,
+		err:      err,
+		done:     make(chan struct{}),
 	}
 	select {
 	case <-b.closed:
@@ -120,7 +344,13 @@ func (b *broadcaster[In, Out]) unregister(listener chan<- Out) {
 }
 
 func (b *broadcaster[In, Out]) run() {
-	defer b.close()
+	reason := ErrBroadcasterClosed
+	defer func() { b.close(reason) }()
+
+	if b.storeErr != nil {
+		reason = b.storeErr
+		return
+	}
 
 	var idleTimer *time.Timer
 	var idle <-chan time.Time
@@ -137,12 +367,17 @@ func (b *broadcaster[In, Out]) run() {
 			idleTimer.Reset(b.idleTimeout)
 		}
 
-		if b.blocking && len(b.listeners) == 0 {
+		for len(b.listeners) < b.minLis {
 			select {
 			case req := <-b.reg:
-				b.listeners[req.channel] = req.opts
+				b.addListener(req)
+			case req := <-b.unreg:
+				b.removeListener(req.listener, req.err)
 				close(req.done)
+			case <-b.stop:
+				return
 			case <-idle:
+				reason = ErrIdleTimeout
 				return
 			}
 		}
@@ -150,96 +385,299 @@ func (b *broadcaster[In, Out]) run() {
 		select {
 		case m, ok := <-b.input:
 			if !ok {
+				b.flushHeld()
 				return
 			}
 			b.broadcast(m)
 
 		case req := <-b.reg:
-			b.listeners[req.channel] = req.opts
-			close(req.done)
+			b.addListener(req)
 
 		case req := <-b.unreg:
-			if _, ok := b.listeners[req.channel]; ok {
-				delete(b.listeners, req.channel)
-				close(req.channel)
-			}
+			b.removeListener(req.listener, req.err)
 			close(req.done)
 
+		case l := <-b.due:
+			b.release(l)
+
+		case l := <-b.caughtUp:
+			b.finishCatchUp(l)
+
+		case <-b.stop:
+			if b.drain {
+				b.flushHeld()
+				b.drainListeners(b.stopCtx)
+			}
+			return
+
 		case <-idle:
+			reason = ErrIdleTimeout
 			return
 		}
 	}
 }
 
+func (b *broadcaster[In, Out]) addListener(req listenerRequest[Out]) {
+	l := req.listener
+	// replaying to every member would duplicate objects within the group
+	if len(l.group) > 0 {
+		g := b.groups[l.group]
+		if g == nil {
+			g = new(group[Out])
+			b.groups[l.group] = g
+		}
+		g.add(l)
+	} else if b.store != nil && (l.startSeq > 0 || !l.startTime.IsZero() || l.resumeAfter != nil) {
+		b.startCatchUp(l)
+	} else {
+		for _, e := range b.history {
+			if l.accepts(e.Value) {
+				l.trySend(e)
+			}
+		}
+	}
+	b.mu.Lock()
+	b.listeners[l] = struct{}{}
+	close(b.joined)
+	b.joined = make(chan struct{})
+	b.mu.Unlock()
+	close(req.done)
+}
+
+func (b *broadcaster[In, Out]) removeListener(l *listener[Out], err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.listeners[l]; ok {
+		delete(b.listeners, l)
+		l.stopCatchUp()
+		l.close(err)
+	}
+	if g := b.groups[l.group]; g != nil {
+		g.remove(l)
+		if len(g.members) == 0 {
+			delete(b.groups, l.group)
+		}
+	}
+}
+
 func (b *broadcaster[In, Out]) broadcast(in In) {
-	if len(b.listeners) == 0 {
+	b.counters.received.Add(1)
+
+	if len(b.listeners) == 0 && b.replay <= 0 && b.store == nil {
+		b.counters.dropped.Add(1)
 		return
 	}
 
 	out, ok := b.convert(in)
 	if !ok {
+		b.counters.rejected.Add(1)
 		return
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(len(b.listeners))
+	var target Target
+	if b.target != nil {
+		target = b.target(in)
+	}
+
+	b.seq++
+	e := Envelope[Out]{
+		Seq:   b.seq,
+		Time:  time.Now(),
+		Value: out,
+	}
+
+	// objects are persisted before they are sent to anyone
+	if b.store != nil && target.isZero() {
+		if err := b.store.Append(e); err != nil {
+			b.seq--
+			b.counters.rejected.Add(1)
+			return
+		}
+	}
 
-	if b.timeout < 0 {
-		for listener := range b.listeners {
-			listener := listener
-			go func() {
-				defer wg.Done()
-				listener <- out
-			}()
+	// targeted objects are not kept for new listeners
+	if b.replay > 0 && target.isZero() {
+		latest := out
+		b.lastOut.Store(&latest)
+		b.history = append(b.history, e)
+		if len(b.history) > b.replay {
+			b.history = b.history[1:]
 		}
-		wg.Wait()
+	}
+
+	if len(b.listeners) == 0 {
+		b.counters.dropped.Add(1)
 		return
 	}
 
-	timeout := make(chan struct{})
-	if b.timeout == 0 {
-		close(timeout)
-	} else {
-		time.AfterFunc(b.timeout, func() { close(timeout) })
+	for l := range b.listeners {
+		if len(l.group) == 0 && target.matches(l.id, l.labels) && l.accepts(out) && l.ready(e) {
+			b.deliver(l, e)
+		}
+	}
+	for _, g := range b.groups {
+		if l := g.pick(out, &target, b.seed); l != nil && l.ready(e) {
+			b.deliver(l, e)
+		}
 	}
 
-	unreg := make(chan chan<- Out, len(b.listeners))
-	for listener, opts := range b.listeners {
-		listener := listener
-		onTimeout := opts.onTimeout
-		go func() {
-			defer wg.Done()
-			select { // try non-blocking first
-			case listener <- out:
-				return
-			default:
+	if len(b.slow) > 0 {
+		b.broadcastSlow(e)
+	}
+}
+
+func (b *broadcaster[In, Out]) release(l *listener[Out]) {
+	if _, ok := b.listeners[l]; !ok {
+		return
+	}
+	if e, ok := l.release(); ok {
+		b.deliver(l, e)
+		if len(b.slow) > 0 {
+			b.broadcastSlow(e)
+		}
+	}
+}
+
+// flushHeld sends the objects held back by throttling or debouncing before the listeners are closed
+func (b *broadcaster[In, Out]) flushHeld() {
+	for l := range b.listeners {
+		if e, ok := l.flush(); ok {
+			b.deliver(l, e)
+			if len(b.slow) > 0 {
+				b.broadcastSlow(e)
 			}
-			select {
-			case listener <- out:
-			case <-timeout:
-				unreg <- listener
-				if onTimeout != nil {
-					go onTimeout()
+		}
+	}
+}
+
+func (b *broadcaster[In, Out]) deliver(l *listener[Out], e Envelope[Out]) {
+	if c := l.catchUp; c != nil {
+		if len(c.pending) < maxCatchUpPending {
+			c.pending = append(c.pending, e)
+			return
+		}
+		l.abortCatchUp()
+	}
+
+	switch l.policy {
+	case DropNewest, DropOldest, Coalesce:
+		if l.sendLossy(e) {
+			b.counters.delivered.Add(1)
+		}
+		return
+	}
+
+	if l.trySend(e) {
+		b.counters.delivered.Add(1)
+		return
+	}
+	b.slow = append(b.slow, l)
+}
+
+func (b *broadcaster[In, Out]) broadcastSlow(e Envelope[Out]) {
+	defer func() {
+		clear(b.slow)
+		b.slow = b.slow[:0]
+	}()
+
+	// disconnecting listeners go first and share the same deadline
+	var timeout <-chan time.Time
+	for _, l := range b.slow {
+		if l.policy != Disconnect {
+			continue
+		}
+
+		if timeout == nil {
+			if b.timeout <= 0 {
+				timeout = expired
+			} else {
+				if b.timer == nil {
+					b.timer = time.NewTimer(b.timeout)
+				} else {
+					b.timer.Reset(b.timeout)
 				}
+				timeout = b.timer.C
+				defer stopTimer(b.timer)
 			}
-		}()
+		}
+
+		if l.send(e, timeout, b.stop) {
+			b.counters.delivered.Add(1)
+			continue
+		}
+
+		select {
+		case <-b.stop:
+			return
+		case <-l.cancelled:
+			continue
+		default:
+		}
+
+		timeout = expired // the deadline passed for the rest of the listeners too
+		b.counters.timedOut.Add(1)
+		b.removeListener(l, ErrListenerTimeout)
+		if l.onTimeout != nil {
+			go l.onTimeout()
+		}
 	}
-	wg.Wait()
-	close(unreg)
-	for listener := range unreg {
-		delete(b.listeners, listener)
-		close(listener)
+
+	for _, l := range b.slow {
+		if l.policy == Block && l.send(e, nil, b.stop) {
+			b.counters.delivered.Add(1)
+		}
 	}
 }
 
-func (b *broadcaster[In, Out]) close() {
+func (b *broadcaster[In, Out]) drainListeners(ctx context.Context) {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for {
+		drained := true
+		for l := range b.listeners {
+			if l.out.len() > 0 {
+				drained = false
+				break
+			}
+		}
+		if drained {
+			return
+		}
+		select {
+		case <-ticker.C:
+		// cancelled listeners won't empty their buffers
+		case req := <-b.unreg:
+			b.removeListener(req.listener, req.err)
+			close(req.done)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *broadcaster[In, Out]) close(reason error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	close(b.closed)
-	for listener := range b.listeners {
-		close(listener)
+	for l := range b.listeners {
+		if l.catchUp != nil {
+			l.closeAfterCatchUp(reason)
+		} else {
+			l.close(reason)
+		}
 	}
 	clear(b.listeners)
 }
 
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
 func noConversion[T any](t T) (T, bool) {
 	return t, true
 }
@@ -2,6 +2,11 @@ package broadcaster_test
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -98,6 +103,59 @@ func TestBlocking(t *testing.T) {
 	}
 }
 
+func TestMinListeners(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithMinListeners(2))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case ch <- 1:
+			t.Fatal("channel should be blocked")
+		case <-time.After(time.Millisecond):
+		}
+
+		_, _, err := b.Listen(WithBufferSize(1))
+		if err != nil {
+			t.Fatalf("unexpected listen error: %v", err)
+		}
+	}
+
+	select {
+	case ch <- 1:
+	case <-time.After(time.Second):
+		t.Fatal("channel should not block")
+	}
+}
+
+func TestWaitForListeners(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := b.WaitForListeners(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("expected err == context.DeadlineExceeded, got '%v'", err)
+	}
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			time.Sleep(time.Millisecond)
+			b.Listen()
+		}
+	}()
+	if err := b.WaitForListeners(context.Background(), 2); err != nil {
+		t.Errorf("unexpected wait error: %v", err)
+	}
+	if stats := b.Stats(); stats.Listeners != 2 {
+		t.Errorf("expected 2 listeners, got %d", stats.Listeners)
+	}
+
+	close(ch)
+	if err := b.WaitForListeners(context.Background(), 3); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+}
+
 func TestListenerClose(t *testing.T) {
 	ch := make(chan int, 1)
 	b := NewBroadcaster(ch)
@@ -197,3 +255,693 @@ func TestIdleTimeoutWithBlocking(t *testing.T) {
 		t.Error("broadcaster should be closed")
 	}
 }
+
+func TestReplay(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithReplay(2))
+
+	ch <- 1
+	ch <- 2
+	ch <- 3
+
+	l, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	go func() { ch <- 4 }()
+
+	for _, expected := range []int{2, 3, 4} {
+		if val := <-l; val != expected {
+			t.Errorf("expected <-l == %d, but got %d", expected, val)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithLatest(true))
+
+	if _, ok := b.Latest(); ok {
+		t.Error("expected no latest value")
+	}
+
+	ch <- 1
+	ch <- 2
+
+	l, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	if val := <-l; val != 2 {
+		t.Errorf("expected <-l == 2, but got %d", val)
+	}
+	if val, ok := b.Latest(); !ok || val != 2 {
+		t.Errorf("expected Latest() == 2, but got %d (%v)", val, ok)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	even := func(val int) bool { return val%2 == 0 }
+	l, _, err := b.Listen(WithFilter(even), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	// odd values are skipped, so they don't time out the listener
+	ch <- 1
+	ch <- 2
+	ch <- 3
+
+	if val := <-l; val != 2 {
+		t.Errorf("expected <-l == 2, but got %d", val)
+	}
+
+	ch <- 4
+	if val, ok := <-l; !ok || val != 4 {
+		t.Errorf("expected <-l == 4, but got %d (%v)", val, ok)
+	}
+}
+
+func TestFilterTypeMismatch(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	_, _, err := b.Listen(WithFilter(func(string) bool { return true }))
+	if err != ErrOptionType {
+		t.Errorf("expected err == ErrOptionType, got '%v'", err)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		expected []int
+	}{
+		{name: "DropNewest", policy: DropNewest, expected: []int{1, 2}},
+		{name: "DropOldest", policy: DropOldest, expected: []int{3, 4}},
+		{name: "Coalesce", policy: Coalesce, expected: []int{4}},
+		{name: "Disconnect", policy: Disconnect, expected: []int{1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan int)
+			b := NewBroadcaster(ch, WithListenerPolicy(tc.policy))
+
+			l, _, err := b.Listen(WithBufferSize(2))
+			if err != nil {
+				t.Fatalf("unexpected listen error: %v", err)
+			}
+
+			for i := 1; i <= 4; i++ {
+				ch <- i
+			}
+			close(ch)
+
+			var got []int
+			for val := range l {
+				got = append(got, val)
+			}
+			if !slices.Equal(tc.expected, got) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestListenerPolicyOverride(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	l, _, err := b.Listen(WithPolicy(Block))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	go func() {
+		ch <- 1
+		close(ch)
+	}()
+
+	time.Sleep(time.Millisecond)
+	if val, ok := <-l; !ok || val != 1 {
+		t.Errorf("expected <-l == 1, but got %d (%v)", val, ok)
+	}
+}
+
+func TestSampleEvery(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	l, _, err := b.Listen(WithSampleEvery(3), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	for i := 1; i <= 7; i++ {
+		ch <- i
+		if i%3 == 1 {
+			if val := <-l; val != i {
+				t.Errorf("expected <-l == %d, but got %d", i, val)
+			}
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	l, _, err := b.Listen(WithThrottle(50*time.Millisecond), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	start := time.Now()
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	if val := <-l; val != 1 {
+		t.Errorf("expected <-l == 1, but got %d", val)
+	}
+	if val := <-l; val != 3 {
+		t.Errorf("expected <-l == 3, but got %d", val)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected throttled value after 50ms, got it after %v", elapsed)
+	}
+	if stats := b.Stats(); stats.Listeners != 1 {
+		t.Error("throttled listener should not time out")
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	l, _, err := b.Listen(WithDebounce(20*time.Millisecond), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ch <- i
+	}
+	if val := <-l; val != 3 {
+		t.Errorf("expected <-l == 3, but got %d", val)
+	}
+
+	ch <- 4
+	select {
+	case val := <-l:
+		t.Errorf("expected no value before the quiet period, got %d", val)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if val := <-l; val != 4 {
+		t.Errorf("expected <-l == 4, but got %d", val)
+	}
+}
+
+func TestDebounceOnClose(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	l, _, err := b.Listen(WithDebounce(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+	ch <- 2
+	close(ch)
+
+	var got []int
+	for val := range l {
+		got = append(got, val)
+	}
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("expected the held value [2], got %v", got)
+	}
+}
+
+func TestConflation(t *testing.T) {
+	ch := make(chan string)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	key := func(val string) byte { return val[0] }
+	l, _, err := b.ListenEnvelopes(WithConflation(key))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- "a1"
+	time.Sleep(time.Millisecond)
+	ch <- "b1"
+	ch <- "a2"
+	ch <- "a3"
+	ch <- "b2"
+	time.Sleep(time.Millisecond)
+
+	expected := []Envelope[string]{
+		{Seq: 1, Value: "a1"},
+		{Seq: 5, Value: "b2", Missed: 1},
+		{Seq: 4, Value: "a3", Missed: 1},
+	}
+	for _, exp := range expected {
+		e := <-l
+		if e.Seq != exp.Seq || e.Value != exp.Value || e.Missed != exp.Missed {
+			t.Errorf("expected %+v, got %+v", exp, e)
+		}
+	}
+	if stats := b.Stats(); stats.Listeners != 1 || stats.TimedOut != 0 {
+		t.Error("conflating listener should not time out")
+	}
+}
+
+func TestConflationOnClose(t *testing.T) {
+	ch := make(chan string)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	key := func(val string) byte { return val[0] }
+	h, err := b.ListenHandle(WithConflation(key))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	kicked, err := b.ListenHandle(WithConflation(key))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- "a1"
+	time.Sleep(time.Millisecond)
+	for _, val := range []string{"b1", "c1", "a2", "b2", "c2"} {
+		ch <- val
+	}
+	time.Sleep(time.Millisecond)
+	kicked.Cancel()
+	close(ch)
+	time.Sleep(time.Millisecond)
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case <-h.Done():
+			t.Fatalf("done before the pending values were received, got %v", got)
+		case val := <-h.C:
+			got = append(got, val)
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"a1", "b2", "c2", "a2"}) {
+		t.Errorf("expected [a1 b2 c2 a2], got %v", got)
+	}
+	if val, ok := <-h.C; ok {
+		t.Errorf("expected closed listener, got %q", val)
+	}
+	<-h.Done()
+
+	if val, ok := <-kicked.C; ok {
+		t.Errorf("expected cancelled listener to be closed, got %q", val)
+	}
+}
+
+func TestConflationTypeMismatch(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	_, _, err := b.Listen(WithConflation(func(string) string { return "" }))
+	if err != ErrOptionType {
+		t.Errorf("expected err == ErrOptionType, got '%v'", err)
+	}
+}
+
+func TestKick(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	a, err := b.ListenHandle(WithListenerID("a"), WithLabels(map[string]string{"role": "admin"}))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	c, err := b.ListenHandle(WithListenerID("c"), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	infos := b.Listeners()
+	slices.SortFunc(infos, func(a, b ListenerInfo) int { return strings.Compare(a.ID, b.ID) })
+	expected := []ListenerInfo{
+		{ID: "a", Labels: map[string]string{"role": "admin"}},
+		{ID: "c"},
+	}
+	if !reflect.DeepEqual(infos, expected) {
+		t.Errorf("expected listeners %v, got %v", expected, infos)
+	}
+	clear(infos[0].Labels)
+	if infos := b.Listeners(); len(infos[0].Labels)+len(infos[1].Labels) != 1 {
+		t.Error("modifying the returned labels should not affect the listener")
+	}
+
+	ch <- 1 // "a" never receives it, so the broadcaster is blocked
+
+	if b.Kick("x", nil) {
+		t.Error("unknown listener should not be kicked")
+	}
+	if !b.Kick("a", nil) {
+		t.Error("listener should be kicked")
+	}
+	<-a.Done()
+	if err := a.Err(); err != ErrListenerKicked {
+		t.Errorf("expected err == ErrListenerKicked, got '%v'", err)
+	}
+
+	ch <- 2
+	for _, expected := range []int{1, 2} {
+		if val := <-c.C; val != expected {
+			t.Errorf("expected <-c.C == %d, but got %d", expected, val)
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	l, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	// blocked listener should not prevent shutdown
+	go func() { ch <- 1 }()
+	time.Sleep(time.Millisecond)
+
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if _, ok := <-l; ok {
+		t.Error("expected l to be closed")
+	}
+	if !b.IsClosed() {
+		t.Error("broadcaster should be closed")
+	}
+	if _, _, err := b.Listen(); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+}
+
+func TestShutdownDrain(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithDrainOnShutdown(true))
+
+	l, _, err := b.Listen(WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected err == context.DeadlineExceeded, got '%v'", err)
+	}
+
+	if val := <-l; val != 1 {
+		t.Errorf("expected <-l == 1, but got %d", val)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if _, ok := <-l; ok {
+		t.Error("expected l to be closed")
+	}
+
+	// a listener that is cancelled instead of read doesn't hold back the shutdown
+	ch = make(chan int)
+	b = NewBroadcaster(ch, WithDrainOnShutdown(true))
+	l, _, err = b.Listen(WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	_, cancelListener, err := b.Listen(WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- b.Shutdown(context.Background()) }()
+	time.Sleep(time.Millisecond)
+	go cancelListener() // blocks until the listener is removed
+	if val := <-l; val != 1 {
+		t.Errorf("expected <-l == 1, but got %d", val)
+	}
+	select {
+	case err := <-shutdown:
+		if err != nil {
+			t.Errorf("unexpected shutdown error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("shutdown should not wait for the cancelled listener")
+	}
+}
+
+func TestStats(t *testing.T) {
+	ch := make(chan int)
+	evenOnly := func(val int) (int, bool) { return val, val%2 == 0 }
+	b := NewConverterBroadcaster(ch, evenOnly, WithTimeout(0))
+
+	ch <- 1 // no listeners
+
+	l, _, err := b.Listen(WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 2
+	ch <- 3 // rejected by converter
+	ch <- 4
+	<-l
+	<-l
+
+	if _, _, err := b.Listen(); err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 6 // times out the unbuffered listener
+
+	// registration is processed after the previous broadcast is done
+	if _, _, err := b.Listen(); err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	stats := b.Stats()
+	expected := Stats{
+		Listeners: 2,
+		Received:  5,
+		Delivered: 3,
+		Dropped:   1,
+		Rejected:  1,
+		TimedOut:  1,
+	}
+	buffers := stats.Buffers
+	stats.Buffers = nil
+	if !reflect.DeepEqual(expected, stats) {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+	slices.SortFunc(buffers, func(a, b BufferStats) int { return a.Cap - b.Cap })
+	if expected := []BufferStats{{0, 0}, {1, 2}}; !slices.Equal(expected, buffers) {
+		t.Errorf("expected buffers %v, got %v", expected, buffers)
+	}
+}
+
+func TestListenHandle(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	canceled, err := b.ListenHandle(WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	ctxCanceled, err := b.ListenHandle(WithBufferSize(1), WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	timedOut, err := b.ListenHandle()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	closed, err := b.ListenHandle(WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	if err := canceled.Err(); err != nil {
+		t.Errorf("expected no error before close, got '%v'", err)
+	}
+
+	ch <- 1
+	canceled.Cancel()
+	cancelCtx()
+	<-ctxCanceled.Done()
+	<-timedOut.Done()
+	close(ch)
+	<-closed.Done()
+
+	for _, tc := range []struct {
+		name     string
+		h        *ListenerHandle[int]
+		expected error
+	}{
+		{"canceled", canceled, context.Canceled},
+		{"ctxCanceled", ctxCanceled, context.Canceled},
+		{"timedOut", timedOut, ErrListenerTimeout},
+		{"closed", closed, ErrBroadcasterClosed},
+	} {
+		if err := tc.h.Err(); err != tc.expected {
+			t.Errorf("%s: expected err == '%v', got '%v'", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestListenHandleIdleTimeout(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithIdleTimeout(time.Millisecond))
+
+	h, err := b.ListenHandle()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	<-h.Done()
+	if err := h.Err(); err != ErrIdleTimeout {
+		t.Errorf("expected err == ErrIdleTimeout, got '%v'", err)
+	}
+	if _, ok := <-h.C; ok {
+		t.Error("expected listener to be closed")
+	}
+}
+
+func TestListenEnvelopes(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithReplay(1))
+
+	ch <- 1
+
+	l1, _, err := b.ListenEnvelopes(WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	l2, _, err := b.ListenEnvelopes(WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 2
+
+	for _, l := range []<-chan Envelope[int]{l1, l2} {
+		for _, expected := range []int{1, 2} {
+			e := <-l
+			if e.Value != expected || e.Seq != uint64(expected) {
+				t.Errorf("expected envelope with value and seq %d, got %+v", expected, e)
+			}
+			if e.Time.IsZero() {
+				t.Error("expected envelope time to be set")
+			}
+		}
+	}
+}
+
+func TestEnvelopeMissed(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithListenerPolicy(DropNewest))
+
+	l, _, err := b.ListenEnvelopes(WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	for i := 1; i <= 4; i++ {
+		ch <- i
+	}
+	for _, expected := range []int{1, 2} {
+		if e := <-l; e.Value != expected || e.Missed != 0 {
+			t.Errorf("expected value %d with nothing missed, got %+v", expected, e)
+		}
+	}
+
+	ch <- 5
+	if e := <-l; e.Value != 5 || e.Missed != 2 {
+		t.Errorf("expected value 5 with 2 missed, got %+v", e)
+	}
+}
+
+func TestTimeoutSharedDeadline(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(timeout))
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := b.Listen(); err != nil {
+			t.Fatalf("unexpected listen error: %v", err)
+		}
+	}
+
+	start := time.Now()
+	ch <- 1
+	// registration is processed after the previous broadcast is done
+	if _, _, err := b.Listen(); err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*timeout {
+		t.Errorf("expected listeners to time out together, took %v", elapsed)
+	}
+	if stats := b.Stats(); stats.TimedOut != 3 {
+		t.Errorf("expected 3 listeners to time out, got %d", stats.TimedOut)
+	}
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("listeners=%d", n), func(b *testing.B) {
+			benchmarkBroadcast(b, n)
+		})
+		b.Run(fmt.Sprintf("listeners=%d/timeout", n), func(b *testing.B) {
+			benchmarkBroadcast(b, n, WithTimeout(time.Second))
+		})
+	}
+}
+
+func benchmarkBroadcast(b *testing.B, numListeners int, opts ...BroadcasterOption) {
+	ch := make(chan int)
+	bc := NewBroadcaster(ch, opts...)
+
+	var wg sync.WaitGroup
+	wg.Add(numListeners)
+	for i := 0; i < numListeners; i++ {
+		l, _, err := bc.Listen(WithBufferSize(16))
+		if err != nil {
+			b.Fatalf("unexpected listen error: %v", err)
+		}
+		go func() {
+			defer wg.Done()
+			for range l {
+			}
+		}()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+}
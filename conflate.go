@@ -0,0 +1,127 @@
+package broadcaster
+
+import (
+	"sync"
+	"time"
+)
+
+type conflatedOutlet[T any, K comparable] struct {
+	out     outlet[T]
+	key     func(T) K
+	mu      sync.Mutex
+	pending map[K]Envelope[T]
+	keys    []K
+	closing bool
+	ready   chan struct{}
+	stop    chan struct{}
+	exited  chan struct{}
+	once    sync.Once
+}
+
+func newConflatedOutlet[T any, K comparable](out outlet[T], key func(T) K) outlet[T] {
+	o := &conflatedOutlet[T, K]{
+		out:     out,
+		key:     key,
+		pending: make(map[K]Envelope[T]),
+		ready:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+func (o *conflatedOutlet[T, K]) run() {
+	defer close(o.exited)
+	defer o.out.close()
+	for {
+		e, ok, closing := o.next()
+		if !ok {
+			if closing {
+				return
+			}
+			select {
+			case <-o.ready:
+				continue
+			case <-o.stop:
+				return
+			}
+		}
+		if !o.out.send(e, nil, o.stop, nil) {
+			return
+		}
+	}
+}
+
+func (o *conflatedOutlet[T, K]) next() (e Envelope[T], ok, closing bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.keys) == 0 {
+		return Envelope[T]{}, false, o.closing
+	}
+	key := o.keys[0]
+	o.keys = o.keys[1:]
+	e = o.pending[key]
+	delete(o.pending, key)
+	return e, true, o.closing
+}
+
+func (o *conflatedOutlet[T, K]) trySend(e Envelope[T]) bool {
+	key := o.key(e.Value)
+
+	o.mu.Lock()
+	if prev, ok := o.pending[key]; ok {
+		// only the latest value of each key is kept
+		e.Missed += prev.Missed + 1
+	} else {
+		o.keys = append(o.keys, key)
+	}
+	o.pending[key] = e
+	o.mu.Unlock()
+
+	o.notify()
+	return true
+}
+
+func (o *conflatedOutlet[T, K]) notify() {
+	select {
+	case o.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (o *conflatedOutlet[T, K]) send(e Envelope[T], timeout <-chan time.Time, stop, cancel <-chan struct{}) bool {
+	return o.trySend(e)
+}
+
+func (o *conflatedOutlet[T, K]) pop() (int, bool) {
+	e, ok, _ := o.next()
+	return e.Missed, ok
+}
+
+func (o *conflatedOutlet[T, K]) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.keys) + o.out.len()
+}
+
+func (o *conflatedOutlet[T, K]) cap() int {
+	return o.out.cap()
+}
+
+// close closes the inner outlet once the pending objects are sent
+func (o *conflatedOutlet[T, K]) close() {
+	o.mu.Lock()
+	o.closing = true
+	o.mu.Unlock()
+	o.notify()
+}
+
+// discard drops the pending objects and closes the inner outlet right away
+func (o *conflatedOutlet[T, K]) discard() {
+	o.once.Do(func() { close(o.stop) })
+}
+
+func (o *conflatedOutlet[T, K]) closed() <-chan struct{} {
+	return o.exited
+}
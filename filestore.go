@@ -0,0 +1,344 @@
+package broadcaster
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentExt       = ".log"
+	recordHeaderSize = 24
+	maxRecordSize    = 1 << 30
+)
+
+var errCorruptRecord = errors.New("corrupt record in store")
+
+type segment struct {
+	path     string
+	firstSeq uint64
+	lastSeq  uint64
+	count    int
+	size     int64
+	lastTime time.Time
+}
+
+type fileStore[T any] struct {
+	storeOptions
+	dir       string
+	marshal   Marshaler
+	unmarshal Unmarshaler
+	mu        sync.Mutex
+	segments  []segment
+	active    *os.File
+	lastSeq   uint64
+	closed    bool
+}
+
+func NewFileStore[T any](dir string, marshaler Marshaler, unmarshaler Unmarshaler, opts ...StoreOption) (Store[T], error) {
+	if marshaler == nil {
+		marshaler = json.Marshal
+	}
+	if unmarshaler == nil {
+		unmarshaler = json.Unmarshal
+	}
+	s := &fileStore[T]{
+		storeOptions: defaultStoreOptions,
+		dir:          dir,
+		marshal:      marshaler,
+		unmarshal:    unmarshaler,
+	}
+	for _, opt := range opts {
+		opt(&s.storeOptions)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileStore[T]) load() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	slices.Sort(paths) // zero padded names sort by their first sequence number
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+		firstSeq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := segment{path: path, firstSeq: firstSeq}
+		seg.size, err = readRecords(path, -1, func(seq uint64, t time.Time, _ []byte) bool {
+			seg.lastSeq = seq
+			seg.lastTime = t
+			seg.count++
+			return true
+		})
+		if err != nil && !errors.Is(err, errCorruptRecord) {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+		s.lastSeq = max(s.lastSeq, seg.lastSeq)
+	}
+
+	if len(s.segments) == 0 {
+		return nil
+	}
+	// a partially written record at the end of the last segment is dropped
+	last := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(last.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(last.size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(last.size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.active = f
+	s.applyRetention(time.Now())
+	return nil
+}
+
+func (s *fileStore[T]) Append(e Envelope[T]) error {
+	payload, err := s.marshal(e.Value)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(record[8:], e.Seq)
+	binary.LittleEndian.PutUint64(record[16:], uint64(e.Time.UnixNano()))
+	copy(record[recordHeaderSize:], payload)
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[8:]))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return os.ErrClosed
+	}
+	if s.active == nil || s.segments[len(s.segments)-1].size >= s.segmentSize {
+		if err := s.rotate(e.Seq); err != nil {
+			return err
+		}
+	}
+	if _, err := s.active.Write(record); err != nil {
+		s.rollback()
+		return err
+	}
+
+	seg := &s.segments[len(s.segments)-1]
+	seg.lastSeq = e.Seq
+	seg.lastTime = e.Time
+	seg.count++
+	seg.size += int64(len(record))
+	s.lastSeq = e.Seq
+
+	s.applyRetention(e.Time)
+	return nil
+}
+
+// rollback drops a partially written record from the end of the active segment,
+// or makes the next append start a new segment if that fails
+func (s *fileStore[T]) rollback() {
+	size := s.segments[len(s.segments)-1].size
+	if err := s.active.Truncate(size); err == nil {
+		if _, err := s.active.Seek(size, io.SeekStart); err == nil {
+			return
+		}
+	}
+	s.active.Close()
+	s.active = nil
+	// the next segment would have the same name
+	if seg := s.segments[len(s.segments)-1]; seg.count == 0 {
+		os.Remove(seg.path)
+		s.segments = s.segments[:len(s.segments)-1]
+	}
+}
+
+func (s *fileStore[T]) rotate(firstSeq uint64) error {
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", firstSeq, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if s.active != nil {
+		s.active.Close()
+	}
+	s.active = f
+	s.segments = append(s.segments, segment{path: path, firstSeq: firstSeq})
+	return nil
+}
+
+// applyRetention deletes whole segments, the active one is always kept
+func (s *fileStore[T]) applyRetention(now time.Time) {
+	var count int
+	var size int64
+	for _, seg := range s.segments {
+		count += seg.count
+		size += seg.size
+	}
+
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		expired := (s.maxCount > 0 && count-oldest.count >= s.maxCount) ||
+			(s.maxBytes > 0 && size > s.maxBytes) ||
+			(s.maxAge > 0 && oldest.lastTime.Before(now.Add(-s.maxAge)))
+		if !expired {
+			return
+		}
+		os.Remove(oldest.path)
+		s.segments = s.segments[1:]
+		count -= oldest.count
+		size -= oldest.size
+	}
+}
+
+func (s *fileStore[T]) Scan(from uint64, fn func(Envelope[T]) bool) error {
+	s.mu.Lock()
+	segments := slices.Clone(s.segments)
+	s.mu.Unlock()
+
+	for i, seg := range segments {
+		if i+1 < len(segments) && segments[i+1].firstSeq <= from {
+			continue
+		}
+
+		var stopped bool
+		var decodeErr error
+		_, err := readRecords(seg.path, seg.size, func(seq uint64, t time.Time, payload []byte) bool {
+			if seq < from {
+				return true
+			}
+			var val T
+			if decodeErr = s.unmarshal(payload, &val); decodeErr != nil {
+				return false
+			}
+			stopped = !fn(Envelope[T]{Seq: seq, Time: t, Value: val})
+			return !stopped
+		})
+		if decodeErr != nil {
+			return decodeErr
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue // deleted by retention in the meantime
+		}
+		if err != nil {
+			return err
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return nil
+}
+
+// seek returns the first sequence number of the oldest segment that may hold objects stored at or after t
+func (s *fileStore[T]) seek(t time.Time) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, seg := range s.segments {
+		if !seg.lastTime.Before(t) {
+			return seg.firstSeq
+		}
+	}
+	return s.lastSeq + 1
+}
+
+func (s *fileStore[T]) LastSeq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeq
+}
+
+func (s *fileStore[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.active != nil {
+		return s.active.Close()
+	}
+	return nil
+}
+
+// readRecords calls fn for each valid record in the first limit bytes of the file
+// (or the whole file if limit is negative) and returns the size of the valid records
+func readRecords(path string, limit int64, fn func(seq uint64, t time.Time, payload []byte) bool) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	br := bufio.NewReader(r)
+
+	var offset int64
+	var header [recordHeaderSize]byte
+	var payload []byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return offset, errCorruptRecord
+			}
+			return offset, err
+		}
+
+		n := binary.LittleEndian.Uint32(header[0:])
+		if n > maxRecordSize {
+			return offset, errCorruptRecord
+		}
+		payload = slices.Grow(payload[:0], int(n))[:n]
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, errCorruptRecord
+			}
+			return offset, err
+		}
+
+		crc := crc32.NewIEEE()
+		crc.Write(header[8:])
+		crc.Write(payload)
+		if crc.Sum32() != binary.LittleEndian.Uint32(header[4:]) {
+			return offset, errCorruptRecord
+		}
+
+		offset += recordHeaderSize + int64(n)
+		seq := binary.LittleEndian.Uint64(header[8:])
+		t := time.Unix(0, int64(binary.LittleEndian.Uint64(header[16:])))
+		if !fn(seq, t, payload) {
+			return offset, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package broadcaster_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore[string](dir, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	for i, val := range []string{"a", "b", "c"} {
+		if err := s.Append(Envelope[string]{Seq: uint64(i + 1), Time: now, Value: val}); err != nil {
+			t.Fatalf("unexpected append error: %v", err)
+		}
+	}
+	if values := scanValues(t, s, 2); !reflect.DeepEqual(values, []string{"b", "c"}) {
+		t.Errorf("expected [b c], got %v", values)
+	}
+	s.Close()
+
+	s, err = NewFileStore[string](dir, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected reopen error: %v", err)
+	}
+	defer s.Close()
+	if seq := s.LastSeq(); seq != 3 {
+		t.Errorf("expected last seq 3, got %d", seq)
+	}
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []string{"a", "b", "c"}) {
+		t.Errorf("expected [a b c], got %v", values)
+	}
+}
+
+func TestFileStoreTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewFileStore[int](dir, nil, nil)
+	s.Append(Envelope[int]{Seq: 1, Time: time.Now(), Value: 1})
+	s.Append(Envelope[int]{Seq: 2, Time: time.Now(), Value: 2})
+	s.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(paths) != 1 {
+		t.Fatalf("expected 1 segment, got %d", len(paths))
+	}
+	info, _ := os.Stat(paths[0])
+	os.Truncate(paths[0], info.Size()-1)
+
+	s, err := NewFileStore[int](dir, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected reopen error: %v", err)
+	}
+	defer s.Close()
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []int{1}) {
+		t.Errorf("expected [1], got %v", values)
+	}
+	s.Append(Envelope[int]{Seq: 2, Time: time.Now(), Value: 3})
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []int{1, 3}) {
+		t.Errorf("expected [1 3], got %v", values)
+	}
+}
+
+func TestFileStoreRetention(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewFileStore[int](dir, nil, nil, WithSegmentSize(1), WithRetentionCount(2))
+	defer s.Close()
+
+	for i := 1; i <= 5; i++ {
+		s.Append(Envelope[int]{Seq: uint64(i), Time: time.Now(), Value: i})
+	}
+
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []int{4, 5}) {
+		t.Errorf("expected [4 5], got %v", values)
+	}
+	if paths, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(paths) != 2 {
+		t.Errorf("expected 2 segments, got %d", len(paths))
+	}
+}
+
+func TestFileStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	s, _ := NewFileStore[int](dir, nil, nil)
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithStore(s))
+	ch <- 1
+	ch <- 2
+	close(ch)
+	<-b.Done()
+	s.Close()
+
+	s, _ = NewFileStore[int](dir, nil, nil)
+	defer s.Close()
+	ch = make(chan int)
+	b = NewBroadcaster(ch, WithStore(s))
+	defer close(ch)
+
+	l, _, _ := b.ListenEnvelopes(WithStartSeq(1))
+	ch <- 3
+
+	for _, seq := range []uint64{1, 2, 3} {
+		if e := <-l; e.Seq != seq || e.Value != int(seq) {
+			t.Errorf("expected seq %d, got %d (%d)", seq, e.Seq, e.Value)
+		}
+	}
+}
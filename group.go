@@ -0,0 +1,44 @@
+package broadcaster
+
+import (
+	"hash/maphash"
+	"slices"
+)
+
+type group[T any] struct {
+	members []*listener[T]
+	next    int
+}
+
+func (g *group[T]) add(l *listener[T]) {
+	g.members = append(g.members, l)
+}
+
+func (g *group[T]) remove(l *listener[T]) {
+	if i := slices.Index(g.members, l); i >= 0 {
+		g.members = slices.Delete(g.members, i, i+1)
+		if g.next > i {
+			g.next--
+		}
+	}
+}
+
+// pick returns the member that receives val, skipping the ones that don't accept it
+func (g *group[T]) pick(val T, target *Target, seed maphash.Seed) *listener[T] {
+	n := len(g.members)
+	start := g.next
+	if key := g.members[0].groupKey; key != nil {
+		start = int(maphash.String(seed, key(val)) % uint64(n))
+	}
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		l := g.members[idx]
+		if target.matches(l.id, l.labels) && l.accepts(val) {
+			if g.members[0].groupKey == nil {
+				g.next = (idx + 1) % n
+			}
+			return l
+		}
+	}
+	return nil
+}
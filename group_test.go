@@ -0,0 +1,82 @@
+package broadcaster_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestGroups(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithListenerBufferSize(4))
+
+	w1, _, _ := b.Listen(WithGroup("workers"))
+	w2, _, _ := b.Listen(WithGroup("workers"))
+	all, _, _ := b.Listen()
+
+	for i := 1; i <= 4; i++ {
+		ch <- i
+	}
+	close(ch)
+
+	for _, tc := range []struct {
+		name     string
+		l        <-chan int
+		expected []int
+	}{
+		{"w1", w1, []int{1, 3}},
+		{"w2", w2, []int{2, 4}},
+		{"all", all, []int{1, 2, 3, 4}},
+	} {
+		var got []int
+		for val := range tc.l {
+			got = append(got, val)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithListenerBufferSize(10))
+
+	key := func(val int) string { return strconv.Itoa(val % 3) }
+	members := make([]<-chan int, 3)
+	for i := range members {
+		members[i], _, _ = b.Listen(WithGroup("workers"), WithGroupKey(key))
+	}
+
+	for i := 0; i < 10; i++ {
+		ch <- i
+	}
+	close(ch)
+
+	owners := make(map[string]int)
+	var count int
+	for i, l := range members {
+		for val := range l {
+			count++
+			if owner, ok := owners[key(val)]; ok && owner != i {
+				t.Errorf("key %s was sent to members %d and %d", key(val), owner, i)
+			}
+			owners[key(val)] = i
+		}
+	}
+	if count != 10 {
+		t.Errorf("expected 10 objects in total, got %d", count)
+	}
+}
+
+func TestGroupKeyTypeMismatch(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	_, _, err := b.Listen(WithGroup("workers"), WithGroupKey(func(string) string { return "" }))
+	if err != ErrOptionType {
+		t.Errorf("expected err == ErrOptionType, got '%v'", err)
+	}
+}
@@ -0,0 +1,386 @@
+package broadcaster
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+type Envelope[T any] struct {
+	Seq    uint64
+	Time   time.Time
+	Value  T
+	Missed int
+}
+
+type ListenerInfo struct {
+	ID     string
+	Labels map[string]string
+}
+
+type ListenerHandle[T any] struct {
+	C      <-chan T
+	Cancel CancelFunc
+	l      *listener[T]
+}
+
+func (h *ListenerHandle[T]) Done() <-chan struct{} {
+	return h.l.done
+}
+
+func (h *ListenerHandle[T]) Err() error {
+	select {
+	case <-h.l.done:
+		return h.l.err
+	default:
+		return nil
+	}
+}
+
+func listenHandle[T any](listen listenFunc[T], opts []ListenerOption) (*ListenerHandle[T], error) {
+	var ch chan T
+	l, cancel, err := listen(opts, func(bufSize int) outlet[T] {
+		ch = make(chan T, bufSize)
+		return valueOutlet[T](ch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ListenerHandle[T]{C: ch, Cancel: cancel, l: l}, nil
+}
+
+func listenEnvelopes[T any](listen listenFunc[T], opts []ListenerOption) (<-chan Envelope[T], CancelFunc, error) {
+	var ch chan Envelope[T]
+	_, cancel, err := listen(opts, func(bufSize int) outlet[T] {
+		ch = make(chan Envelope[T], bufSize)
+		return envelopeOutlet[T](ch)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, cancel, nil
+}
+
+type listenFunc[T any] func(opts []ListenerOption, newOutlet func(bufSize int) outlet[T]) (*listener[T], CancelFunc, error)
+
+type listener[T any] struct {
+	id          string
+	labels      map[string]string
+	group       string
+	groupKey    func(T) string
+	out         outlet[T]
+	policy      Policy
+	onTimeout   func()
+	filter      func(T) bool
+	conflate    func(outlet[T]) outlet[T]
+	missed      int
+	done        chan struct{}
+	err         error
+	cancel      func(error)
+	cancelled   chan struct{}
+	startSeq    uint64
+	startTime   time.Time
+	resumeAfter func(Envelope[T]) bool
+	catchUp     *catchUp[T]
+
+	sample   int
+	sampled  int
+	throttle time.Duration
+	debounce time.Duration
+	lastSent time.Time
+	pending  Envelope[T]
+	held     bool
+	dueAt    time.Time
+	timer    *time.Timer
+	wake     func()
+}
+
+func newListener[T any](bo *broadcasterOptions, opts []ListenerOption) (*listener[T], listenerOptions, error) {
+	lisOpts := listenerOptions{
+		bufSize: bo.lisBufSize,
+	}
+	for _, opt := range opts {
+		opt(&lisOpts)
+	}
+
+	l := &listener[T]{
+		id:        lisOpts.id,
+		labels:    lisOpts.labels,
+		group:     lisOpts.group,
+		startSeq:  lisOpts.startSeq,
+		startTime: lisOpts.startTime,
+		policy:    lisOpts.policy,
+		onTimeout: lisOpts.onTimeout,
+		done:      make(chan struct{}),
+		sample:    lisOpts.sample,
+		throttle:  lisOpts.throttle,
+		debounce:  lisOpts.debounce,
+	}
+	if l.policy == 0 {
+		l.policy = bo.lisPolicy
+	}
+	if l.policy == 0 {
+		if bo.timeout < 0 {
+			l.policy = Block
+		} else {
+			l.policy = Disconnect
+		}
+	}
+	if lisOpts.filter != nil {
+		filter, ok := lisOpts.filter.(func(T) bool)
+		if !ok {
+			return nil, lisOpts, ErrOptionType
+		}
+		l.filter = filter
+	}
+	if lisOpts.resumeAfter != nil {
+		resumeAfter, ok := lisOpts.resumeAfter.(func(Envelope[T]) bool)
+		if !ok {
+			return nil, lisOpts, ErrOptionType
+		}
+		l.resumeAfter = resumeAfter
+	}
+	if lisOpts.groupKey != nil {
+		groupKey, ok := lisOpts.groupKey.(func(T) string)
+		if !ok {
+			return nil, lisOpts, ErrOptionType
+		}
+		l.groupKey = groupKey
+	}
+	if lisOpts.conflate != nil {
+		conflate, ok := lisOpts.conflate.(func(outlet[T]) outlet[T])
+		if !ok {
+			return nil, lisOpts, ErrOptionType
+		}
+		l.conflate = conflate
+	}
+	return l, lisOpts, nil
+}
+
+func (l *listener[T]) info() ListenerInfo {
+	return ListenerInfo{
+		ID:     l.id,
+		Labels: maps.Clone(l.labels),
+	}
+}
+
+func (l *listener[T]) attach(out outlet[T]) {
+	if l.conflate != nil {
+		out = l.conflate(out)
+	}
+	l.out = out
+}
+
+func (l *listener[T]) cancelOnDone(ctx context.Context, cancel func(error)) {
+	select {
+	case <-ctx.Done():
+		cancel(ctx.Err())
+	case <-l.done:
+	}
+}
+
+func (l *listener[T]) close(err error) {
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	// objects of cancelled or kicked listeners aren't delivered anymore
+	if out, ok := l.out.(interface{ discard() }); ok && isClosed(l.cancelled) {
+		out.discard()
+	}
+	l.out.close()
+	l.err = err
+	// some outlets are closed only after the pending objects are sent
+	if out, ok := l.out.(interface{ closed() <-chan struct{} }); ok {
+		go func() {
+			<-out.closed()
+			close(l.done)
+		}()
+		return
+	}
+	close(l.done)
+}
+
+func (l *listener[T]) accepts(val T) bool {
+	return l.filter == nil || l.filter(val)
+}
+
+// ready reports whether e should be sent right away,
+// otherwise it may be held back until wake is called
+func (l *listener[T]) ready(e Envelope[T]) bool {
+	if l.sample > 1 {
+		l.sampled++
+		if (l.sampled-1)%l.sample != 0 {
+			return false
+		}
+	}
+	if l.throttle <= 0 && l.debounce <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	dueAt := l.lastSent.Add(l.throttle)
+	if quiet := now.Add(l.debounce); quiet.After(dueAt) {
+		dueAt = quiet
+	}
+	if !l.held && !dueAt.After(now) {
+		l.lastSent = now
+		return true
+	}
+
+	l.pending = e
+	l.held = true
+	l.dueAt = dueAt
+	if l.timer == nil {
+		l.timer = time.AfterFunc(dueAt.Sub(now), l.wake)
+	} else {
+		l.timer.Reset(dueAt.Sub(now))
+	}
+	return false
+}
+
+func (l *listener[T]) release() (Envelope[T], bool) {
+	now := time.Now()
+	if !l.held || l.dueAt.After(now) {
+		return Envelope[T]{}, false
+	}
+	e := l.pending
+	l.pending = Envelope[T]{}
+	l.held = false
+	l.lastSent = now
+	return e, true
+}
+
+// flush returns the held object without waiting for its due time
+func (l *listener[T]) flush() (Envelope[T], bool) {
+	l.dueAt = time.Time{}
+	return l.release()
+}
+
+func (l *listener[T]) trySend(e Envelope[T]) bool {
+	e.Missed = l.missed
+	if l.out.trySend(e) {
+		l.missed = 0
+		return true
+	}
+	return false
+}
+
+func (l *listener[T]) send(e Envelope[T], timeout <-chan time.Time, stop <-chan struct{}) bool {
+	e.Missed = l.missed
+	if l.out.send(e, timeout, stop, l.cancelled) {
+		l.missed = 0
+		return true
+	}
+	return false
+}
+
+func (l *listener[T]) sendLossy(e Envelope[T]) bool {
+	switch l.policy {
+	case DropOldest:
+		for !l.trySend(e) {
+			if !l.dropOldest() { // unbuffered channel without a receiver
+				break
+			}
+		}
+	case Coalesce:
+		// pending values are replaced by the latest one
+		for l.dropOldest() {
+		}
+	}
+	if l.trySend(e) {
+		return true
+	}
+	l.missed++
+	return false
+}
+
+func (l *listener[T]) dropOldest() bool {
+	missed, ok := l.out.pop()
+	if ok {
+		l.missed += missed + 1
+	}
+	return ok
+}
+
+type outlet[T any] interface {
+	trySend(e Envelope[T]) bool
+	send(e Envelope[T], timeout <-chan time.Time, stop, cancel <-chan struct{}) bool
+	pop() (missed int, ok bool)
+	len() int
+	cap() int
+	close()
+}
+
+type valueOutlet[T any] chan T
+
+func (o valueOutlet[T]) trySend(e Envelope[T]) bool {
+	select {
+	case o <- e.Value:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o valueOutlet[T]) send(e Envelope[T], timeout <-chan time.Time, stop, cancel <-chan struct{}) bool {
+	select {
+	case o <- e.Value:
+		return true
+	case <-timeout:
+		return false
+	case <-stop:
+		return false
+	case <-cancel:
+		return false
+	}
+}
+
+func (o valueOutlet[T]) pop() (int, bool) {
+	select {
+	case <-o:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func (o valueOutlet[T]) len() int { return len(o) }
+func (o valueOutlet[T]) cap() int { return cap(o) }
+func (o valueOutlet[T]) close()   { close(o) }
+
+type envelopeOutlet[T any] chan Envelope[T]
+
+func (o envelopeOutlet[T]) trySend(e Envelope[T]) bool {
+	select {
+	case o <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o envelopeOutlet[T]) send(e Envelope[T], timeout <-chan time.Time, stop, cancel <-chan struct{}) bool {
+	select {
+	case o <- e:
+		return true
+	case <-timeout:
+		return false
+	case <-stop:
+		return false
+	case <-cancel:
+		return false
+	}
+}
+
+func (o envelopeOutlet[T]) pop() (int, bool) {
+	select {
+	case e := <-o:
+		return e.Missed, true
+	default:
+		return 0, false
+	}
+}
+
+func (o envelopeOutlet[T]) len() int { return len(o) }
+func (o envelopeOutlet[T]) cap() int { return cap(o) }
+func (o envelopeOutlet[T]) close()   { close(o) }
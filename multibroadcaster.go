@@ -1,6 +1,8 @@
 package broadcaster
 
 import (
+	"context"
+	"slices"
 	"sync"
 )
 
@@ -8,61 +10,164 @@ type MultiSource[K comparable, T any] func(K) (<-chan T, CancelFunc, error)
 
 type MultiBroadcaster[K comparable, T any] interface {
 	Listen(key K, opts ...ListenerOption) (<-chan T, CancelFunc, error)
+	ListenHandle(key K, opts ...ListenerOption) (*ListenerHandle[T], error)
+	ListenEnvelopes(key K, opts ...ListenerOption) (<-chan Envelope[T], CancelFunc, error)
+	Listeners() []ListenerInfo
+	Kick(id string, reason error) bool
+	Shutdown(ctx context.Context) error
 }
 
 type multiBroadcaster[K comparable, In, Out any] struct {
-	mu   sync.Mutex
-	bcs  map[K]Broadcaster[Out]
-	src  MultiSource[K, In]
-	conv Converter[In, Out]
-	opts []BroadcasterOption
+	mu      sync.Mutex
+	bcs     map[K]Broadcaster[Out]
+	src     MultiSource[K, In]
+	conv    Converter[In, Out]
+	opts    []BroadcasterOption
+	keyOpts func(K) []BroadcasterOption
+	wg      sync.WaitGroup
+	stop    bool
+	err     error
 }
 
 func NewMultiBroadcaster[K comparable, T any](src MultiSource[K, T], opts ...BroadcasterOption) MultiBroadcaster[K, T] {
-	return &multiBroadcaster[K, T, T]{
-		bcs:  make(map[K]Broadcaster[T]),
-		src:  src,
-		conv: noConversion[T],
-		opts: opts,
-	}
+	return newMultiBroadcaster(src, noConversion[T], opts)
 }
 
 func NewMultiConverterBroadcaster[K comparable, In, Out any](src MultiSource[K, In], conv Converter[In, Out], opts ...BroadcasterOption) MultiBroadcaster[K, Out] {
-	return &multiBroadcaster[K, In, Out]{
+	return newMultiBroadcaster(src, conv, opts)
+}
+
+func newMultiBroadcaster[K comparable, In, Out any](src MultiSource[K, In], conv Converter[In, Out], opts []BroadcasterOption) *multiBroadcaster[K, In, Out] {
+	mb := &multiBroadcaster[K, In, Out]{
 		bcs:  make(map[K]Broadcaster[Out]),
 		src:  src,
 		conv: conv,
 		opts: opts,
 	}
+	// the broadcasters of different keys would append their own sequence numbers to the same store
+	if applyBroadcasterOptions(opts).storage != nil {
+		mb.err = ErrSharedStore
+	}
+	return mb
 }
 
 func (mb *multiBroadcaster[K, In, Out]) Listen(key K, opts ...ListenerOption) (<-chan Out, CancelFunc, error) {
+	h, err := mb.ListenHandle(key, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.C, h.Cancel, nil
+}
+
+func (mb *multiBroadcaster[K, In, Out]) ListenHandle(key K, opts ...ListenerOption) (*ListenerHandle[Out], error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	bc, err := mb.broadcaster(key)
+	if err != nil {
+		return nil, err
+	}
+	return bc.ListenHandle(opts...)
+}
+
+func (mb *multiBroadcaster[K, In, Out]) ListenEnvelopes(key K, opts ...ListenerOption) (<-chan Envelope[Out], CancelFunc, error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
+	bc, err := mb.broadcaster(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bc.ListenEnvelopes(opts...)
+}
+
+func (mb *multiBroadcaster[K, In, Out]) broadcaster(key K) (Broadcaster[Out], error) {
+	if mb.stop {
+		return nil, ErrBroadcasterClosed
+	}
+	if mb.err != nil {
+		return nil, mb.err
+	}
 
 	if bc := mb.bcs[key]; bc != nil && !bc.IsClosed() {
-		return bc.Listen(opts...)
+		return bc, nil
 	}
 
 	input, cancel, err := mb.src(key)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	bc := NewConverterBroadcaster(input, mb.conv, mb.opts...)
+	opts := mb.opts
+	if mb.keyOpts != nil {
+		opts = append(slices.Clip(opts), mb.keyOpts(key)...)
+	}
+	bc := NewConverterBroadcaster(input, mb.conv, opts...)
 	mb.bcs[key] = bc
 
+	mb.wg.Add(1)
 	go func() {
+		defer mb.wg.Done()
 		<-bc.Done()
 		cancel()
 
 		mb.mu.Lock()
 		defer mb.mu.Unlock()
 		bc = mb.bcs[key]
-		if bc.IsClosed() {
+		if bc != nil && bc.IsClosed() {
 			delete(mb.bcs, key)
 		}
 	}()
 
-	return bc.Listen(opts...)
+	return bc, nil
+}
+
+func (mb *multiBroadcaster[K, In, Out]) Listeners() []ListenerInfo {
+	var infos []ListenerInfo
+	for _, bc := range mb.broadcasters() {
+		infos = append(infos, bc.Listeners()...)
+	}
+	return infos
+}
+
+func (mb *multiBroadcaster[K, In, Out]) Kick(id string, reason error) bool {
+	var kicked bool
+	for _, bc := range mb.broadcasters() {
+		if bc.Kick(id, reason) {
+			kicked = true
+		}
+	}
+	return kicked
+}
+
+func (mb *multiBroadcaster[K, In, Out]) broadcasters() []Broadcaster[Out] {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	bcs := make([]Broadcaster[Out], 0, len(mb.bcs))
+	for _, bc := range mb.bcs {
+		bcs = append(bcs, bc)
+	}
+	return bcs
+}
+
+func (mb *multiBroadcaster[K, In, Out]) Shutdown(ctx context.Context) error {
+	mb.mu.Lock()
+	mb.stop = true
+	mb.mu.Unlock()
+	bcs := mb.broadcasters()
+
+	for _, bc := range bcs {
+		go bc.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		mb.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
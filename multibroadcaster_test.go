@@ -1,7 +1,9 @@
 package broadcaster_test
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	. "github.com/razzie/broadcaster"
@@ -44,3 +46,52 @@ func multiSource(key string) (<-chan int, CancelFunc, error) {
 		return nil, nil, ErrExpected
 	}
 }
+
+func TestMultiBroadcasterShutdown(t *testing.T) {
+	var canceled atomic.Int32
+	src := func(key string) (<-chan int, CancelFunc, error) {
+		return make(chan int), func() { canceled.Add(1) }, nil
+	}
+	b := NewMultiBroadcaster(src)
+
+	l1, _, err := b.Listen("1")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	l2, _, err := b.Listen("2")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if canceled.Load() != 2 {
+		t.Errorf("expected 2 sources to be canceled, got %d", canceled.Load())
+	}
+	if _, ok := <-l1; ok {
+		t.Error("expected l1 to be closed")
+	}
+	if _, ok := <-l2; ok {
+		t.Error("expected l2 to be closed")
+	}
+	if _, _, err := b.Listen("1"); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+}
+
+func TestMultiBroadcasterSharedStore(t *testing.T) {
+	var started atomic.Int32
+	src := func(key string) (<-chan int, CancelFunc, error) {
+		started.Add(1)
+		return make(chan int), func() {}, nil
+	}
+	b := NewMultiBroadcaster(src, WithStore(NewMemoryStore[int]()))
+
+	if _, _, err := b.Listen("1"); err != ErrSharedStore {
+		t.Errorf("expected err == ErrSharedStore, got '%v'", err)
+	}
+	if started.Load() != 0 {
+		t.Error("source should not be started")
+	}
+}
@@ -9,7 +9,7 @@ type MultiEventSource[K comparable] interface {
 	GetEventSource(K) (<-chan Event, CancelFunc, error)
 }
 
-func NewMultiSSEBroadcaster[K comparable](src MultiEventSource[K], opts ...BroadcasterOption) http.Handler {
+func NewMultiSSEBroadcaster[K comparable](src MultiEventSource[K], opts ...BroadcasterOption) SSEHandler {
 	source := func(key K) (<-chan Event, CancelFunc, error) {
 		events, cancel, err := src.GetEventSource(key)
 		if err != nil {
@@ -17,16 +17,18 @@ func NewMultiSSEBroadcaster[K comparable](src MultiEventSource[K], opts ...Broad
 		}
 		return events, cancel, nil
 	}
-	b := NewMultiConverterBroadcaster(source, marshalEvent, opts...)
-	listen := func(r *http.Request) (<-chan string, error) {
+	bo := applyBroadcasterOptions(opts)
+	b := newMultiBroadcaster(source, marshalEvent, sseOptions(opts))
+	// each key has its own history, which is dropped along with its broadcaster
+	// so the histories of keys that are no longer used don't pile up
+	b.keyOpts = func(K) []BroadcasterOption {
+		return []BroadcasterOption{eventHistory(bo.eventHistory)}
+	}
+	return newSSEHandler(b, bo, func(r *http.Request, opts []ListenerOption) (<-chan Envelope[EncodedEvent], CancelFunc, error) {
 		key, err := src.GetKey(r)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		l, _, err := b.Listen(key, WithContext(r.Context()))
-		return l, err
-	}
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serveSSE(listen, w, r)
+		return b.ListenEnvelopes(key, opts...)
 	})
 }
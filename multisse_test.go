@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	. "github.com/razzie/broadcaster"
 )
@@ -22,14 +23,52 @@ func TestMultiSSEBroadcaster(t *testing.T) {
 	resp1 := runRequest(mux, "/sse/1")
 	resp2 := runRequest(mux, "/sse/2")
 
-	if expected, got := "data: 1\n\n", <-resp1; expected != got {
+	if expected, got := "id: 1\ndata: 1\n\n", <-resp1; expected != got {
 		t.Errorf("expected <-resp1 == %q, got %q", expected, got)
 	}
-	if expected, got := "data: 2\n\n", <-resp2; expected != got {
+	if expected, got := "id: 1\ndata: 2\n\n", <-resp2; expected != got {
 		t.Errorf("expected <-resp2 == %q, got %q", expected, got)
 	}
 }
 
+func TestMultiSSEBroadcasterResume(t *testing.T) {
+	ch := make(chan string)
+	b := NewMultiSSEBroadcaster(liveEventSource{ch})
+
+	resp1 := runRequest(b, "/")
+	time.Sleep(time.Millisecond)
+	ch <- "a"
+	ch <- "b"
+	time.Sleep(time.Millisecond)
+
+	resp2 := runRequest(b, "/", "Last-Event-ID", "1")
+	time.Sleep(time.Millisecond)
+	ch <- "c"
+	close(ch)
+
+	if expected, got := "id: 1\ndata: a\n\nid: 2\ndata: b\n\nid: 3\ndata: c\n\n", <-resp1; expected != got {
+		t.Errorf("expected <-resp1 == %q, got %q", expected, got)
+	}
+	if expected, got := "id: 2\ndata: b\n\nid: 3\ndata: c\n\n", <-resp2; expected != got {
+		t.Errorf("expected <-resp2 == %q, got %q", expected, got)
+	}
+}
+
+func TestMultiSSEBroadcasterHistoryDropped(t *testing.T) {
+	b := NewMultiSSEBroadcaster(new(multiEventSource), WithBlocking(true))
+	mux := http.NewServeMux()
+	mux.Handle("GET /sse/{key}", b)
+
+	<-runRequest(mux, "/sse/1")
+	time.Sleep(time.Millisecond)
+
+	// the history of the closed broadcaster is gone, so the event IDs start over
+	resp := runRequest(mux, "/sse/1", "Last-Event-ID", "0")
+	if expected, got := "id: 1\ndata: 1\n\n", <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
+
 func TestMultiSSEBroadcasterBadKey(t *testing.T) {
 	b := NewMultiSSEBroadcaster(new(multiEventSource))
 
@@ -71,3 +110,15 @@ func (src multiEventSource) GetEventSource(key string) (<-chan Event, CancelFunc
 	close(ch)
 	return NewTextEventSource(ch, ""), func() {}, nil
 }
+
+type liveEventSource struct {
+	ch <-chan string
+}
+
+func (src liveEventSource) GetKey(r *http.Request) (string, error) {
+	return "", nil
+}
+
+func (src liveEventSource) GetEventSource(key string) (<-chan Event, CancelFunc, error) {
+	return NewTextEventSource(src.ch, ""), func() {}, nil
+}
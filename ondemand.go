@@ -1,6 +1,7 @@
 package broadcaster
 
 import (
+	"context"
 	"sync"
 )
 
@@ -12,6 +13,7 @@ type ondemandBroadcaster[In, Out any] struct {
 	src     Source[In]
 	convert Converter[In, Out]
 	opts    []BroadcasterOption
+	stopped bool
 }
 
 func NewOndemandBroadcaster[T any](src Source[T], opts ...BroadcasterOption) Broadcaster[T] {
@@ -31,16 +33,45 @@ func NewOndemandConverterBroadcaster[In, Out any](src Source[In], convert Conver
 }
 
 func (ob *ondemandBroadcaster[In, Out]) Listen(opts ...ListenerOption) (<-chan Out, CancelFunc, error) {
+	h, err := ob.ListenHandle(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.C, h.Cancel, nil
+}
+
+func (ob *ondemandBroadcaster[In, Out]) ListenHandle(opts ...ListenerOption) (*ListenerHandle[Out], error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	b, err := ob.broadcaster()
+	if err != nil {
+		return nil, err
+	}
+	return b.ListenHandle(opts...)
+}
+
+func (ob *ondemandBroadcaster[In, Out]) ListenEnvelopes(opts ...ListenerOption) (<-chan Envelope[Out], CancelFunc, error) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
+	b, err := ob.broadcaster()
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.ListenEnvelopes(opts...)
+}
+
+func (ob *ondemandBroadcaster[In, Out]) broadcaster() (Broadcaster[Out], error) {
+	if ob.stopped {
+		return nil, ErrBroadcasterClosed
+	}
 	if ob.b == nil || ob.b.IsClosed() {
 		in, err := ob.src()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		ob.b = NewConverterBroadcaster(in, ob.convert, ob.opts...)
 	}
-	return ob.b.Listen(opts...)
+	return ob.b, nil
 }
 
 func (ob *ondemandBroadcaster[In, Out]) IsClosed() bool {
@@ -55,3 +86,61 @@ func (ob *ondemandBroadcaster[In, Out]) IsClosed() bool {
 func (ob *ondemandBroadcaster[In, Out]) Done() <-chan struct{} {
 	return nil
 }
+
+func (ob *ondemandBroadcaster[In, Out]) Latest() (Out, bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.b != nil {
+		return ob.b.Latest()
+	}
+	var zero Out
+	return zero, false
+}
+
+func (ob *ondemandBroadcaster[In, Out]) Listeners() []ListenerInfo {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.b != nil {
+		return ob.b.Listeners()
+	}
+	return nil
+}
+
+func (ob *ondemandBroadcaster[In, Out]) Kick(id string, reason error) bool {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.b != nil {
+		return ob.b.Kick(id, reason)
+	}
+	return false
+}
+
+func (ob *ondemandBroadcaster[In, Out]) WaitForListeners(ctx context.Context, n int) error {
+	ob.mu.Lock()
+	b, err := ob.broadcaster()
+	ob.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return b.WaitForListeners(ctx, n)
+}
+
+func (ob *ondemandBroadcaster[In, Out]) Shutdown(ctx context.Context) error {
+	ob.mu.Lock()
+	ob.stopped = true
+	b := ob.b
+	ob.mu.Unlock()
+	if b != nil {
+		return b.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (ob *ondemandBroadcaster[In, Out]) Stats() Stats {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.b != nil {
+		return ob.b.Stats()
+	}
+	return Stats{}
+}
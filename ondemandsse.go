@@ -6,7 +6,7 @@ import (
 
 type OndemandEventSource func() (<-chan Event, error)
 
-func NewOndemandSSEBroadcaster(src OndemandEventSource, opts ...BroadcasterOption) http.Handler {
+func NewOndemandSSEBroadcaster(src OndemandEventSource, opts ...BroadcasterOption) SSEHandler {
 	source := func() (<-chan Event, error) {
 		events, err := src()
 		if err != nil {
@@ -14,12 +14,11 @@ func NewOndemandSSEBroadcaster(src OndemandEventSource, opts ...BroadcasterOptio
 		}
 		return events, nil
 	}
+	bo := applyBroadcasterOptions(opts)
+	// the history outlives the underlying broadcasters, so event IDs keep increasing
+	opts = append(sseOptions(opts), eventHistory(bo.eventHistory))
 	b := NewOndemandConverterBroadcaster(source, marshalEvent, opts...)
-	listen := func(r *http.Request) (<-chan string, error) {
-		l, _, err := b.Listen(WithContext(r.Context()))
-		return l, err
-	}
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serveSSE(listen, w, r)
+	return newSSEHandler(b, bo, func(r *http.Request, opts []ListenerOption) (<-chan Envelope[EncodedEvent], CancelFunc, error) {
+		return b.ListenEnvelopes(opts...)
 	})
 }
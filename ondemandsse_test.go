@@ -17,7 +17,25 @@ func TestOndemandSSEBroadcaster(t *testing.T) {
 	b := NewOndemandSSEBroadcaster(src, WithBlocking(true))
 
 	resp := runRequest(b, "/")
-	if expected, got := "data: a\n\n", <-resp; expected != got {
+	if expected, got := "id: 1\ndata: a\n\n", <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
+
+func TestOndemandSSEBroadcasterResume(t *testing.T) {
+	src := func() (<-chan Event, error) {
+		ch := make(chan string, 1)
+		ch <- "a"
+		close(ch)
+		return NewTextEventSource(ch, ""), nil
+	}
+	b := NewOndemandSSEBroadcaster(src, WithBlocking(true))
+
+	if expected, got := "id: 1\ndata: a\n\n", <-runRequest(b, "/"); expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+	// event IDs continue after the source was restarted
+	if expected, got := "id: 1\ndata: a\n\nid: 2\ndata: a\n\n", <-runRequest(b, "/", "Last-Event-ID", "0"); expected != got {
 		t.Errorf("expected <-resp == %q, got %q", expected, got)
 	}
 }
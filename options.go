@@ -3,13 +3,15 @@ package broadcaster
 import (
 	"context"
 	"io"
+	"maps"
 	"net/http"
 	"time"
 )
 
 var (
 	defaultBroadcasterOptions = broadcasterOptions{
-		timeout: -1,
+		timeout:      -1,
+		eventHistory: 100,
 	}
 
 	defaultSSEListenerOptions = sseListenerOptions{
@@ -18,11 +20,32 @@ var (
 	}
 )
 
+type Policy int
+
+const (
+	Block Policy = iota + 1
+	Disconnect
+	DropNewest
+	DropOldest
+	Coalesce
+)
+
 type broadcasterOptions struct {
 	timeout     time.Duration
 	lisBufSize  int
 	blocking    bool
+	minLis      int
 	idleTimeout time.Duration
+	replay      int
+	latest      bool
+	lisPolicy   Policy
+	drain       bool
+	reqOpts     func(*http.Request) []ListenerOption
+	route       any
+	storage     any
+
+	eventHistory int
+	heartbeat    time.Duration
 }
 
 type BroadcasterOption func(*broadcasterOptions)
@@ -45,16 +68,87 @@ func WithBlocking(blocking bool) BroadcasterOption {
 	}
 }
 
+func WithMinListeners(n int) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.minLis = n
+	}
+}
+
 func WithIdleTimeout(timeout time.Duration) BroadcasterOption {
 	return func(bo *broadcasterOptions) {
 		bo.idleTimeout = timeout
 	}
 }
 
+func WithReplay(n int) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.replay = n
+	}
+}
+
+func WithLatest(latest bool) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.latest = latest
+	}
+}
+
+func WithListenerPolicy(policy Policy) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.lisPolicy = policy
+	}
+}
+
+func WithDrainOnShutdown(drain bool) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.drain = drain
+	}
+}
+
+func WithStore[T any](store Store[T]) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.storage = store
+	}
+}
+
+func WithEventHistory(n int) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.eventHistory = n
+	}
+}
+
+func WithHeartbeat(interval time.Duration) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.heartbeat = interval
+	}
+}
+
+func WithRequestListenerOptions(reqOpts func(r *http.Request) []ListenerOption) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.reqOpts = reqOpts
+	}
+}
+
 type listenerOptions struct {
-	ctx       context.Context
-	onTimeout func()
-	bufSize   int
+	ctx         context.Context
+	onTimeout   func()
+	bufSize     int
+	filter      any
+	policy      Policy
+	throttle    time.Duration
+	debounce    time.Duration
+	sample      int
+	conflate    any
+	id          string
+	labels      map[string]string
+	group       string
+	groupKey    any
+	ackTimeout  time.Duration
+	maxRetries  int
+	maxUnacked  int
+	deadLetter  any
+	startSeq    uint64
+	startTime   time.Time
+	resumeAfter any
 }
 
 type ListenerOption func(*listenerOptions)
@@ -77,6 +171,112 @@ func WithTimeoutCallback(onTimeout func()) ListenerOption {
 	}
 }
 
+func WithFilter[T any](filter func(T) bool) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.filter = filter
+	}
+}
+
+func WithPolicy(policy Policy) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.policy = policy
+	}
+}
+
+func WithThrottle(interval time.Duration) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.throttle = interval
+	}
+}
+
+func WithDebounce(quiet time.Duration) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.debounce = quiet
+	}
+}
+
+func WithSampleEvery(n int) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.sample = n
+	}
+}
+
+func WithConflation[T any, K comparable](key func(T) K) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.conflate = func(out outlet[T]) outlet[T] {
+			return newConflatedOutlet(out, key)
+		}
+	}
+}
+
+func WithListenerID(id string) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.id = id
+	}
+}
+
+func WithLabels(labels map[string]string) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.labels = maps.Clone(labels)
+	}
+}
+
+func WithGroup(name string) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.group = name
+	}
+}
+
+func WithGroupKey[T any](key func(T) string) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.groupKey = key
+	}
+}
+
+func WithAckTimeout(timeout time.Duration) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.ackTimeout = timeout
+	}
+}
+
+func WithMaxRetries(n int) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.maxRetries = n
+	}
+}
+
+func WithMaxUnacked(n int) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.maxUnacked = n
+	}
+}
+
+func WithDeadLetter[T any](deadLetter func(Envelope[T])) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.deadLetter = deadLetter
+	}
+}
+
+func WithStartSeq(seq uint64) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.startSeq = seq
+	}
+}
+
+func WithStartTime(t time.Time) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.startTime = t
+	}
+}
+
+// withResumeAfter makes the listener catch up from the stored object after the one matching last,
+// or from the oldest stored object if there is no match
+func withResumeAfter[T any](last func(Envelope[T]) bool) ListenerOption {
+	return func(lo *listenerOptions) {
+		lo.resumeAfter = last
+	}
+}
+
 type sseListenerOptions struct {
 	client          *http.Client
 	method          string
@@ -124,3 +324,20 @@ func WithEventsBufferSize(bufSize int) SSEListenerOption {
 		slo.bufSize = bufSize
 	}
 }
+
+type eventSourceOptions struct {
+	id func(any) string
+}
+
+type EventSourceOption func(*eventSourceOptions)
+
+func WithEventID[T any](id func(T) string) EventSourceOption {
+	return func(eso *eventSourceOptions) {
+		eso.id = func(val any) string {
+			if val, ok := val.(T); ok {
+				return id(val)
+			}
+			return ""
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package broadcaster
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAckTimeout = 30 * time.Second
+	defaultMaxUnacked = 1024
+)
+
+type Delivery[T any] struct {
+	Envelope[T]
+	Attempt int
+	acks    chan<- uint64
+	done    <-chan struct{}
+}
+
+func (d Delivery[T]) Ack() {
+	select {
+	case d.acks <- d.Seq:
+	case <-d.done:
+	}
+}
+
+type unacked[T any] struct {
+	d        Delivery[T]
+	deadline time.Time
+}
+
+func ListenReliable[T any](b Broadcaster[T], opts ...ListenerOption) (<-chan Delivery[T], CancelFunc, error) {
+	lisOpts := listenerOptions{
+		ackTimeout: defaultAckTimeout,
+		maxRetries: -1,
+	}
+	for _, opt := range opts {
+		opt(&lisOpts)
+	}
+	// a non-positive timeout would make every delivery expire right away
+	if lisOpts.ackTimeout <= 0 {
+		lisOpts.ackTimeout = defaultAckTimeout
+	}
+	if lisOpts.maxUnacked <= 0 {
+		lisOpts.maxUnacked = defaultMaxUnacked
+	}
+	var deadLetter func(Envelope[T])
+	if lisOpts.deadLetter != nil {
+		var ok bool
+		if deadLetter, ok = lisOpts.deadLetter.(func(Envelope[T])); !ok {
+			return nil, nil, ErrOptionType
+		}
+	}
+
+	// objects must not be dropped before they reach the consumer,
+	// the listener is drained right away so it doesn't hold back the broadcaster
+	input, cancel, err := b.ListenEnvelopes(append(opts, WithPolicy(Block))...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deliveries := make(chan Delivery[T])
+	acks := make(chan uint64)
+	stop := make(chan struct{})
+	r := &reliable[T]{
+		input:      input,
+		deliveries: deliveries,
+		acks:       acks,
+		stop:       stop,
+		exited:     make(chan struct{}),
+		ackTimeout: lisOpts.ackTimeout,
+		maxRetries: lisOpts.maxRetries,
+		maxUnacked: lisOpts.maxUnacked,
+		deadLetter: deadLetter,
+		unacked:    make(map[uint64]*unacked[T]),
+	}
+	go r.run()
+
+	var once sync.Once
+	return deliveries, func() {
+		once.Do(func() {
+			close(stop)
+			cancel()
+		})
+	}, nil
+}
+
+type reliable[T any] struct {
+	input      <-chan Envelope[T]
+	deliveries chan Delivery[T]
+	acks       chan uint64
+	stop       chan struct{}
+	exited     chan struct{}
+	ackTimeout time.Duration
+	maxRetries int
+	maxUnacked int
+	deadLetter func(Envelope[T])
+	queue      []Delivery[T]
+	unacked    map[uint64]*unacked[T]
+}
+
+func (r *reliable[T]) run() {
+	defer close(r.deliveries)
+	// late acks must not block once nobody receives them
+	defer close(r.exited)
+
+	timer := time.NewTimer(r.ackTimeout)
+	defer timer.Stop()
+
+	input := r.input
+	for input != nil || len(r.queue) > 0 || len(r.unacked) > 0 {
+		var deliveries chan Delivery[T]
+		var next Delivery[T]
+		if len(r.queue) > 0 {
+			deliveries = r.deliveries
+			next = r.queue[0]
+		}
+		select {
+		case e, ok := <-input:
+			if !ok {
+				input = nil
+				continue
+			}
+			// the consumer is too far behind to hold on to more objects
+			if len(r.queue)+len(r.unacked) >= r.maxUnacked {
+				if r.deadLetter != nil {
+					r.deadLetter(e)
+				}
+				continue
+			}
+			r.queue = append(r.queue, Delivery[T]{
+				Envelope: e,
+				Attempt:  1,
+				acks:     r.acks,
+				done:     r.exited,
+			})
+
+		case deliveries <- next:
+			r.queue = r.queue[1:]
+			r.unacked[next.Seq] = &unacked[T]{
+				d:        next,
+				deadline: time.Now().Add(r.ackTimeout),
+			}
+			if len(r.unacked) == 1 {
+				stopTimer(timer)
+				timer.Reset(r.ackTimeout)
+			}
+
+		case seq := <-r.acks:
+			r.ack(seq)
+
+		case <-timer.C:
+			r.expire(timer)
+
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *reliable[T]) ack(seq uint64) {
+	delete(r.unacked, seq)
+	// the object might be waiting for redelivery already
+	r.queue = slices.DeleteFunc(r.queue, func(d Delivery[T]) bool {
+		return d.Seq == seq
+	})
+}
+
+func (r *reliable[T]) expire(timer *time.Timer) {
+	now := time.Now()
+	next := now.Add(r.ackTimeout)
+	for seq, u := range r.unacked {
+		if u.deadline.After(now) {
+			if u.deadline.Before(next) {
+				next = u.deadline
+			}
+			continue
+		}
+		delete(r.unacked, seq)
+		if r.maxRetries >= 0 && u.d.Attempt > r.maxRetries {
+			if r.deadLetter != nil {
+				r.deadLetter(u.d.Envelope)
+			}
+			continue
+		}
+		u.d.Attempt++
+		r.queue = append(r.queue, u.d)
+	}
+	timer.Reset(next.Sub(now))
+}
@@ -0,0 +1,154 @@
+package broadcaster_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestListenReliable(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithTimeout(0))
+
+	deadLetters := make(chan Envelope[int], 1)
+	l, _, err := ListenReliable(b, WithAckTimeout(10*time.Millisecond), WithMaxRetries(1),
+		WithDeadLetter(func(e Envelope[int]) { deadLetters <- e }))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+	ch <- 2
+	close(ch)
+
+	if d := <-l; d.Value != 1 || d.Attempt != 1 {
+		t.Errorf("expected first attempt of 1, got %+v", d)
+	} else {
+		d.Ack()
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		if d := <-l; d.Value != 2 || d.Attempt != attempt {
+			t.Errorf("expected attempt %d of 2, got %+v", attempt, d)
+		}
+	}
+
+	if e := <-deadLetters; e.Value != 2 {
+		t.Errorf("expected 2 as dead letter, got %+v", e)
+	}
+	if d, ok := <-l; ok {
+		t.Errorf("expected deliveries to be closed, got %+v", d)
+	}
+}
+
+func TestListenReliableAck(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	l, cancel, err := ListenReliable(b, WithAckTimeout(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	defer cancel()
+
+	ch <- 1
+	d := <-l
+	time.Sleep(10 * time.Millisecond)
+	d.Ack() // acked after the deadline, but before it's received again
+	d.Ack()
+
+	ch <- 2
+	if d := <-l; d.Value != 2 {
+		t.Errorf("expected 2, got %+v", d)
+	}
+}
+
+func TestListenReliableLateAck(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	l, _, err := ListenReliable(b, WithAckTimeout(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+	first := <-l
+	(<-l).Ack() // the redelivery
+	close(ch)
+	for range l {
+	}
+
+	acked := make(chan struct{})
+	go func() {
+		first.Ack()
+		close(acked)
+	}()
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Error("late ack blocked")
+	}
+}
+
+func TestListenReliableZeroAckTimeout(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch)
+
+	l, cancel, err := ListenReliable(b, WithAckTimeout(0))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	defer cancel()
+
+	ch <- 1
+	if d := <-l; d.Attempt != 1 {
+		t.Errorf("expected first attempt, got %+v", d)
+	}
+	select {
+	case d := <-l:
+		t.Errorf("expected no redelivery before the default timeout, got %+v", d)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestListenReliableDoesNotBlock(t *testing.T) {
+	ch := make(chan int)
+	defer close(ch)
+	b := NewBroadcaster(ch, WithTimeout(10*time.Millisecond))
+
+	deadLetters := make(chan Envelope[int], 10)
+	r, cancel, err := ListenReliable(b, WithMaxUnacked(2),
+		WithDeadLetter(func(e Envelope[int]) { deadLetters <- e }))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	defer cancel()
+	l, _, err := b.Listen(WithBufferSize(100))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+	<-r // the consumer is busy and never acks
+
+	for i := 2; i <= 10; i++ {
+		select {
+		case ch <- i:
+		case <-time.After(time.Second):
+			t.Fatalf("broadcaster was blocked at %d by the reliable listener", i)
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		if val := <-l; val != i {
+			t.Errorf("expected <-l == %d, got %d", i, val)
+		}
+	}
+
+	// 1 is waiting for an ack and 2 is queued
+	for i := 3; i <= 10; i++ {
+		if e := <-deadLetters; e.Value != i {
+			t.Errorf("expected %d as dead letter, got %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package broadcaster
+
+import (
+	"context"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ringBroadcaster[T any] struct {
+	broadcasterOptions
+	input    <-chan T
+	slots    []atomic.Pointer[Envelope[T]]
+	mask     uint64
+	cursor   atomic.Uint64
+	waiters  atomic.Int32
+	notify   atomic.Pointer[chan struct{}]
+	mu       sync.Mutex
+	readers  map[*ringReader[T]]struct{}
+	active   atomic.Int32
+	joined   chan struct{}
+	waiting  chan struct{}
+	wg       sync.WaitGroup
+	counters counters
+	finished bool
+	reason   error
+	eof      chan struct{}
+	closed   chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type ringReader[T any] struct {
+	l      *listener[T]
+	next   uint64
+	due    chan struct{}
+	stop   chan struct{}
+	err    error
+	cancel sync.Once
+	kicked sync.Once
+}
+
+func NewRingBroadcaster[T any](input <-chan T, size int, opts ...BroadcasterOption) Broadcaster[T] {
+	size = 1 << bits.Len(uint(max(size, 1)-1))
+	notify := make(chan struct{})
+	rb := &ringBroadcaster[T]{
+		broadcasterOptions: defaultBroadcasterOptions,
+		input:              input,
+		slots:              make([]atomic.Pointer[Envelope[T]], size),
+		mask:               uint64(size - 1),
+		readers:            make(map[*ringReader[T]]struct{}),
+		joined:             make(chan struct{}, 1),
+		waiting:            make(chan struct{}),
+		eof:                make(chan struct{}),
+		closed:             make(chan struct{}),
+		stop:               make(chan struct{}),
+	}
+	rb.notify.Store(&notify)
+	for _, opt := range opts {
+		opt(&rb.broadcasterOptions)
+	}
+	if rb.latest && rb.replay < 1 {
+		rb.replay = 1
+	}
+	if rb.blocking && rb.minLis < 1 {
+		rb.minLis = 1
+	}
+	go rb.run()
+	return rb
+}
+
+func (rb *ringBroadcaster[T]) Listen(opts ...ListenerOption) (<-chan T, CancelFunc, error) {
+	h, err := rb.ListenHandle(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.C, h.Cancel, nil
+}
+
+func (rb *ringBroadcaster[T]) ListenHandle(opts ...ListenerOption) (*ListenerHandle[T], error) {
+	return listenHandle(rb.listen, opts)
+}
+
+func (rb *ringBroadcaster[T]) ListenEnvelopes(opts ...ListenerOption) (<-chan Envelope[T], CancelFunc, error) {
+	return listenEnvelopes(rb.listen, opts)
+}
+
+func (rb *ringBroadcaster[T]) listen(opts []ListenerOption, newOutlet func(bufSize int) outlet[T]) (*listener[T], CancelFunc, error) {
+	l, lisOpts, err := newListener[T](&rb.broadcasterOptions, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.attach(newOutlet(lisOpts.bufSize))
+	l.cancelled = make(chan struct{})
+
+	r := &ringReader[T]{
+		l:    l,
+		due:  make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+	l.wake = func() {
+		select {
+		case r.due <- struct{}{}:
+		default:
+		}
+	}
+
+	rb.mu.Lock()
+	if rb.finished || isClosed(rb.stop) {
+		rb.mu.Unlock()
+		l.out.close()
+		return nil, nil, ErrBroadcasterClosed
+	}
+	r.next = rb.start()
+	rb.readers[r] = struct{}{}
+	rb.active.Add(1)
+	rb.wg.Add(1)
+	close(rb.waiting)
+	rb.waiting = make(chan struct{})
+	rb.mu.Unlock()
+
+	select {
+	case rb.joined <- struct{}{}:
+	default:
+	}
+	go rb.read(r)
+
+	if lisOpts.ctx != nil {
+		go l.cancelOnDone(lisOpts.ctx, r.kick)
+	}
+
+	return l, func() { r.kick(context.Canceled) }, nil
+}
+
+func (rb *ringBroadcaster[T]) IsClosed() bool {
+	return isClosed(rb.closed)
+}
+
+func (rb *ringBroadcaster[T]) Done() <-chan struct{} {
+	return rb.closed
+}
+
+func (rb *ringBroadcaster[T]) Latest() (T, bool) {
+	if cursor := rb.cursor.Load(); cursor > 0 {
+		if e := rb.slots[cursor&rb.mask].Load(); e != nil {
+			return e.Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func (rb *ringBroadcaster[T]) Listeners() []ListenerInfo {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	infos := make([]ListenerInfo, 0, len(rb.readers))
+	for r := range rb.readers {
+		infos = append(infos, r.l.info())
+	}
+	return infos
+}
+
+func (rb *ringBroadcaster[T]) Kick(id string, reason error) bool {
+	if len(id) == 0 {
+		return false
+	}
+	if reason == nil {
+		reason = ErrListenerKicked
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	var kicked bool
+	for r := range rb.readers {
+		if r.l.id == id {
+			r.kick(reason)
+			kicked = true
+		}
+	}
+	return kicked
+}
+
+func (rb *ringBroadcaster[T]) WaitForListeners(ctx context.Context, n int) error {
+	for {
+		rb.mu.Lock()
+		count, waiting := len(rb.readers), rb.waiting
+		rb.mu.Unlock()
+		if count >= n {
+			return nil
+		}
+
+		select {
+		case <-waiting:
+		case <-rb.closed:
+			return ErrBroadcasterClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (rb *ringBroadcaster[T]) Shutdown(ctx context.Context) error {
+	rb.stopOnce.Do(func() { close(rb.stop) })
+	if !rb.drain {
+		rb.closeReaders(ErrBroadcasterClosed)
+	}
+	select {
+	case <-rb.closed:
+		return nil
+	case <-ctx.Done():
+		rb.closeReaders(ErrBroadcasterClosed)
+		return ctx.Err()
+	}
+}
+
+func (rb *ringBroadcaster[T]) Stats() Stats {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	stats := Stats{
+		Listeners: len(rb.readers),
+		Received:  rb.counters.received.Load(),
+		Delivered: rb.counters.delivered.Load(),
+		Dropped:   rb.counters.dropped.Load(),
+		Buffers:   make([]BufferStats, 0, len(rb.readers)),
+	}
+	for r := range rb.readers {
+		stats.Buffers = append(stats.Buffers, BufferStats{
+			Len: r.l.out.len(),
+			Cap: r.l.out.cap(),
+		})
+	}
+	return stats
+}
+
+func (rb *ringBroadcaster[T]) start() uint64 {
+	next := rb.cursor.Load() + 1
+	replay := min(uint64(max(rb.replay, 0)), rb.mask+1)
+	if next > replay {
+		return next - replay
+	}
+	return 1
+}
+
+func (rb *ringBroadcaster[T]) run() {
+	rb.finish(rb.write())
+}
+
+func (rb *ringBroadcaster[T]) write() error {
+	var idleTimer *time.Timer
+	var idle <-chan time.Time
+	if rb.idleTimeout > 0 {
+		idleTimer = time.NewTimer(rb.idleTimeout)
+		idle = idleTimer.C
+	}
+
+	for {
+		if idleTimer != nil {
+			stopTimer(idleTimer)
+			idleTimer.Reset(rb.idleTimeout)
+		}
+
+		for rb.active.Load() < int32(rb.minLis) {
+			select {
+			case <-rb.joined:
+			case <-rb.stop:
+				return ErrBroadcasterClosed
+			case <-idle:
+				return ErrIdleTimeout
+			}
+		}
+
+		select {
+		case val, ok := <-rb.input:
+			if !ok {
+				return ErrBroadcasterClosed
+			}
+			rb.publish(val)
+		case <-rb.stop:
+			return ErrBroadcasterClosed
+		case <-idle:
+			return ErrIdleTimeout
+		}
+	}
+}
+
+func (rb *ringBroadcaster[T]) publish(val T) {
+	rb.counters.received.Add(1)
+	if rb.active.Load() == 0 {
+		rb.counters.dropped.Add(1)
+	}
+
+	seq := rb.cursor.Load() + 1
+	rb.slots[seq&rb.mask].Store(&Envelope[T]{
+		Seq:   seq,
+		Time:  time.Now(),
+		Value: val,
+	})
+	rb.cursor.Store(seq)
+
+	// readers increment waiters before checking the cursor,
+	// so either they see the new cursor or we see them waiting
+	if rb.waiters.Load() > 0 {
+		notify := make(chan struct{})
+		close(*rb.notify.Swap(&notify))
+	}
+}
+
+func (rb *ringBroadcaster[T]) finish(reason error) {
+	rb.mu.Lock()
+	rb.finished = true
+	rb.reason = reason
+	close(rb.eof)
+	rb.mu.Unlock()
+
+	rb.wg.Wait()
+	close(rb.closed)
+}
+
+func (rb *ringBroadcaster[T]) closeReaders(err error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	for r := range rb.readers {
+		r.close(err)
+	}
+}
+
+func (rb *ringBroadcaster[T]) read(r *ringReader[T]) {
+	err := rb.follow(r)
+
+	rb.mu.Lock()
+	delete(rb.readers, r)
+	rb.active.Add(-1)
+	rb.mu.Unlock()
+
+	r.l.close(err)
+	rb.wg.Done()
+}
+
+func (rb *ringBroadcaster[T]) follow(r *ringReader[T]) error {
+	for {
+		for cursor := rb.cursor.Load(); r.next <= cursor; cursor = rb.cursor.Load() {
+			e := rb.slots[r.next&rb.mask].Load()
+			if e.Seq != r.next { // lapped by the writer
+				oldest := cursor - rb.mask
+				r.l.missed += int(oldest - r.next)
+				r.next = oldest
+				continue
+			}
+			r.next++
+
+			if !r.l.accepts(e.Value) || !r.l.ready(*e) {
+				continue
+			}
+			if !rb.send(r, *e) {
+				return r.err
+			}
+		}
+
+		rb.waiters.Add(1)
+		notify := *rb.notify.Load()
+		if r.next <= rb.cursor.Load() {
+			rb.waiters.Add(-1)
+			continue
+		}
+		select {
+		case <-notify:
+		case <-r.due:
+			if e, ok := r.l.release(); ok && !rb.send(r, e) {
+				rb.waiters.Add(-1)
+				return r.err
+			}
+		case <-r.stop:
+			rb.waiters.Add(-1)
+			return r.err
+		case <-rb.eof:
+			if r.next > rb.cursor.Load() {
+				rb.waiters.Add(-1)
+				if e, ok := r.l.flush(); ok && !rb.send(r, e) {
+					return r.err
+				}
+				return rb.reason
+			}
+		}
+		rb.waiters.Add(-1)
+	}
+}
+
+func (rb *ringBroadcaster[T]) send(r *ringReader[T], e Envelope[T]) bool {
+	if !r.l.send(e, nil, r.stop) {
+		return false
+	}
+	rb.counters.delivered.Add(1)
+	return true
+}
+
+// kick closes the reader without sending the objects held back by its listener
+func (r *ringReader[T]) kick(err error) {
+	r.kicked.Do(func() { close(r.l.cancelled) })
+	r.close(err)
+}
+
+func (r *ringReader[T]) close(err error) {
+	r.cancel.Do(func() {
+		r.err = err
+		close(r.stop)
+	})
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
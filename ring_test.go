@@ -0,0 +1,166 @@
+package broadcaster_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestRingBroadcast(t *testing.T) {
+	const numMessages = 5
+
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 8)
+
+	l1, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	l2, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	go func() {
+		for i := 1; i <= numMessages; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	for _, l := range []<-chan int{l1, l2} {
+		for i := 1; i <= numMessages; i++ {
+			if val := <-l; val != i {
+				t.Errorf("expected <-l == %d, but got %d", i, val)
+			}
+		}
+		if _, ok := <-l; ok {
+			t.Error("expected listener to be closed")
+		}
+	}
+
+	<-b.Done()
+	if _, _, err := b.Listen(); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+}
+
+func TestRingLapped(t *testing.T) {
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 4)
+
+	l, _, err := b.ListenEnvelopes()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1 // taken by the reader, which then blocks on the listener
+	for i := 2; i <= 10; i++ {
+		ch <- i
+	}
+	close(ch)
+
+	var missed, last int
+	for e := range l {
+		if e.Value != last+e.Missed+1 {
+			t.Errorf("expected value %d after %d missed, got %+v", last+e.Missed+1, e.Missed, e)
+		}
+		missed += e.Missed
+		last = e.Value
+	}
+	if last != 10 {
+		t.Errorf("expected last value 10, got %d", last)
+	}
+	if missed == 0 {
+		t.Error("expected lapped listener to report missed messages")
+	}
+}
+
+func TestRingReplay(t *testing.T) {
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 4, WithReplay(2))
+
+	for i := 1; i <= 3; i++ {
+		ch <- i
+	}
+	// the value received last may not be published yet
+	for {
+		if val, ok := b.Latest(); ok && val == 3 {
+			break
+		}
+	}
+
+	l, _, err := b.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	for _, expected := range []int{2, 3} {
+		if val := <-l; val != expected {
+			t.Errorf("expected <-l == %d, but got %d", expected, val)
+		}
+	}
+}
+
+func TestRingShutdown(t *testing.T) {
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 4)
+
+	h, err := b.ListenHandle()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1 // blocked listener should not prevent shutdown
+
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if err := h.Err(); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+	if !b.IsClosed() {
+		t.Error("broadcaster should be closed")
+	}
+}
+
+func TestRingDebounce(t *testing.T) {
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 4)
+
+	l, _, err := b.Listen(WithDebounce(20 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ch <- i
+	}
+	if val := <-l; val != 3 {
+		t.Errorf("expected <-l == 3, but got %d", val)
+	}
+}
+
+func TestRingDebounceOnClose(t *testing.T) {
+	ch := make(chan int)
+	b := NewRingBroadcaster(ch, 4)
+
+	l, _, err := b.Listen(WithDebounce(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ch <- 1
+	ch <- 2
+	close(ch)
+
+	var got []int
+	for val := range l {
+		got = append(got, val)
+	}
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("expected the held value [2], got %v", got)
+	}
+}
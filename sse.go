@@ -3,11 +3,14 @@ package broadcaster
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"html/template"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -15,10 +18,51 @@ type Event interface {
 	Read() (name, data string)
 }
 
+type EventWithID interface {
+	Event
+	EventID() string
+}
+
+type EventWithRetry interface {
+	Event
+	EventRetry() time.Duration
+}
+
+type EventWithComment interface {
+	Event
+	EventComment() string
+}
+
+type SSEEvent struct {
+	Name    string
+	Data    string
+	ID      string
+	Retry   time.Duration
+	Comment string
+}
+
+func (e SSEEvent) Read() (name, data string) {
+	return e.Name, e.Data
+}
+
+func (e SSEEvent) EventID() string {
+	return e.ID
+}
+
+func (e SSEEvent) EventRetry() time.Duration {
+	return e.Retry
+}
+
+func (e SSEEvent) EventComment() string {
+	return e.Comment
+}
+
 type event struct {
 	name      string
 	data      any
+	id        string
 	marshaler Marshaler
+	target    Target
 }
 
 func (e event) Read() (name, data string) {
@@ -29,36 +73,51 @@ func (e event) Read() (name, data string) {
 	return e.name, string(bytes)
 }
 
+func (e event) EventID() string {
+	return e.id
+}
+
 type Marshaler func(any) ([]byte, error)
 
-func NewEventSource[T any](input <-chan T, eventName string, marshaler Marshaler) <-chan Event {
+func NewEventSource[T any](input <-chan T, eventName string, marshaler Marshaler, opts ...EventSourceOption) <-chan Event {
 	if marshaler == nil {
 		marshaler = json.Marshal
 	}
+	var eso eventSourceOptions
+	for _, opt := range opts {
+		opt(&eso)
+	}
 	events := make(chan Event)
 	go func() {
 		defer close(events)
 		for in := range input {
-			events <- event{
+			e := event{
 				name:      eventName,
 				data:      in,
 				marshaler: marshaler,
 			}
+			if t, ok := any(in).(interface{ route() (Target, any) }); ok {
+				e.target, e.data = t.route()
+			}
+			if eso.id != nil {
+				e.id = eso.id(e.data)
+			}
+			events <- e
 		}
 	}()
 	return events
 }
 
-func NewJsonEventSource[T any](input <-chan T, eventName string) <-chan Event {
-	return NewEventSource(input, eventName, json.Marshal)
+func NewJsonEventSource[T any](input <-chan T, eventName string, opts ...EventSourceOption) <-chan Event {
+	return NewEventSource(input, eventName, json.Marshal, opts...)
 }
 
-func NewTextEventSource(input <-chan string, eventName string) <-chan Event {
-	return NewEventSource(input, eventName, marshalText)
+func NewTextEventSource(input <-chan string, eventName string, opts ...EventSourceOption) <-chan Event {
+	return NewEventSource(input, eventName, marshalText, opts...)
 }
 
-func NewTemplateEventSource[T any](input <-chan T, eventName string, t *template.Template, templateName string) <-chan Event {
-	return NewEventSource(input, eventName, marshalTemplate(t, templateName))
+func NewTemplateEventSource[T any](input <-chan T, eventName string, t *template.Template, templateName string, opts ...EventSourceOption) <-chan Event {
+	return NewEventSource(input, eventName, marshalTemplate(t, templateName), opts...)
 }
 
 func BundleEventSources(srcs ...<-chan Event) <-chan Event {
@@ -81,54 +140,217 @@ func BundleEventSources(srcs ...<-chan Event) <-chan Event {
 	return events
 }
 
-func NewSSEBroadcaster(src <-chan Event, opts ...BroadcasterOption) http.Handler {
-	b := NewConverterBroadcaster(src, marshalEvent, opts...)
-	listen := func(r *http.Request) (<-chan string, error) {
-		l, _, err := b.Listen(WithContext(r.Context()))
-		return l, err
+type SSEHandler interface {
+	http.Handler
+	Listeners() []ListenerInfo
+	Kick(id string, reason error) bool
+}
+
+type listenerSet interface {
+	Listeners() []ListenerInfo
+	Kick(id string, reason error) bool
+}
+
+type EncodedEvent struct {
+	ID     string
+	Text   string
+	NoData bool
+}
+
+type sseListen func(r *http.Request, opts []ListenerOption) (<-chan Envelope[EncodedEvent], CancelFunc, error)
+
+type sseHandler struct {
+	listenerSet
+	listen    sseListen
+	reqOpts   func(*http.Request) []ListenerOption
+	heartbeat time.Duration
+}
+
+func newSSEHandler(b listenerSet, bo broadcasterOptions, listen sseListen) *sseHandler {
+	return &sseHandler{
+		listenerSet: b,
+		listen:      listen,
+		reqOpts:     bo.reqOpts,
+		heartbeat:   bo.heartbeat,
 	}
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serveSSE(listen, w, r)
-	})
 }
 
-func serveSSE(listen func(*http.Request) (<-chan string, error), w http.ResponseWriter, r *http.Request) {
-	ww, _ := w.(interface {
-		http.Flusher
-		io.StringWriter
+func NewSSEBroadcaster(src <-chan Event, opts ...BroadcasterOption) SSEHandler {
+	bo := applyBroadcasterOptions(opts)
+	opts = append(sseOptions(opts), eventHistory(bo.eventHistory))
+	b := NewConverterBroadcaster(src, marshalEvent, opts...)
+	return newSSEHandler(b, bo, func(r *http.Request, opts []ListenerOption) (<-chan Envelope[EncodedEvent], CancelFunc, error) {
+		return b.ListenEnvelopes(opts...)
 	})
+}
+
+func sseOptions(opts []BroadcasterOption) []BroadcasterOption {
+	return append([]BroadcasterOption{withRoute(eventTarget)}, opts...)
+}
+
+func eventTarget(e Event) Target {
+	if e, ok := e.(event); ok {
+		return e.target
+	}
+	return Target{}
+}
 
-	events, err := listen(r)
+// eventHistory keeps the last n events for clients that reconnect with a Last-Event-ID header,
+// unless the broadcaster already has a store
+func eventHistory(n int) BroadcasterOption {
+	if n <= 0 {
+		return func(*broadcasterOptions) {}
+	}
+	store := NewMemoryStore[EncodedEvent](WithRetentionCount(n))
+	return func(bo *broadcasterOptions) {
+		if bo.storage == nil {
+			bo.storage = store
+		}
+	}
+}
+
+func applyBroadcasterOptions(opts []BroadcasterOption) broadcasterOptions {
+	bo := defaultBroadcasterOptions
+	for _, opt := range opts {
+		opt(&bo)
+	}
+	return bo
+}
+
+func (h *sseHandler) listenerOptions(r *http.Request) []ListenerOption {
+	lisOpts := []ListenerOption{WithContext(r.Context())}
+	if id := r.Header.Get("Last-Event-ID"); len(id) > 0 {
+		lisOpts = append(lisOpts, withResumeAfter(func(e Envelope[EncodedEvent]) bool {
+			return eventID(e) == id
+		}))
+	}
+	if h.reqOpts != nil {
+		lisOpts = append(lisOpts, h.reqOpts(r)...)
+	}
+	return lisOpts
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	events, cancel, err := h.listen(r, h.listenerOptions(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// a failed write means the client is gone
+	defer cancel()
 
 	w.Header().Add("Cache-Control", "no-store")
 	w.Header().Add("Content-Type", "text/event-stream")
 	w.Header().Set("Connection", "keep-alive")
 	w.WriteHeader(http.StatusOK)
 
-	if ww != nil {
-		for e := range events {
-			ww.WriteString(e)
-			ww.Flush()
+	// heartbeats are only sent while there are no events
+	heartbeat := h.heartbeat
+	var ticker *time.Ticker
+	var ticks <-chan time.Time
+	if heartbeat > 0 {
+		ticker = time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	rc := http.NewResponseController(w)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			_, err = io.WriteString(w, marshalEnvelope(e))
+			// events that are already buffered are flushed together
+			for n := len(events); n > 0 && err == nil; n-- {
+				_, err = io.WriteString(w, marshalEnvelope(<-events))
+			}
+			if ticker != nil {
+				ticker.Reset(heartbeat)
+			}
+
+		case <-ticks:
+			_, err = io.WriteString(w, ":\n\n")
 		}
-		return
+
+		if err == nil {
+			if err = rc.Flush(); errors.Is(err, http.ErrNotSupported) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// eventID returns the ID of the event or its sequence number if it has none
+func eventID(e Envelope[EncodedEvent]) string {
+	if len(e.Value.ID) > 0 {
+		return e.Value.ID
 	}
+	return strconv.FormatUint(e.Seq, 10)
+}
 
-	for e := range events {
-		w.Write([]byte(e))
+func marshalEnvelope(e Envelope[EncodedEvent]) string {
+	var lagged string
+	if e.Missed > 0 {
+		lagged = "event: lagged\ndata: " + strconv.Itoa(e.Missed) + "\n\n"
+	}
+	// events without data only set the client's last event ID to their own ID
+	if e.Value.NoData && len(e.Value.ID) == 0 {
+		return lagged + e.Value.Text
 	}
+	return lagged + "id: " + eventID(e) + "\n" + e.Value.Text
 }
 
-func marshalEvent(e Event) (string, bool) {
+func marshalEvent(e Event) (EncodedEvent, bool) {
 	name, data := e.Read()
-	data = strings.ReplaceAll(data, "\n", "\ndata: ")
+	var encoded EncodedEvent
+	if e, ok := e.(EventWithID); ok {
+		// the ID would end the field or be ignored by the client
+		if id := e.EventID(); !strings.ContainsAny(id, "\r\n\x00") {
+			encoded.ID = id
+		}
+	}
+	var sb strings.Builder
+	if e, ok := e.(EventWithRetry); ok {
+		if retry := e.EventRetry(); retry > 0 {
+			sb.WriteString("retry: " + strconv.FormatInt(retry.Milliseconds(), 10) + "\n")
+		}
+	}
+	if e, ok := e.(EventWithComment); ok {
+		if comment := e.EventComment(); len(comment) > 0 {
+			sb.WriteString(": " + prefixLines(comment, ": ") + "\n")
+		}
+	}
+	// the name can't be split into lines like the data
+	if strings.ContainsAny(name, "\r\n") {
+		name = ""
+	}
 	if len(name) > 0 {
-		return "event: " + name + "\ndata: " + data + "\n\n", true
+		sb.WriteString("event: " + name + "\n")
+	}
+	// comments, retry times and IDs can be sent on their own
+	if len(data) == 0 && len(name) == 0 && (sb.Len() > 0 || len(encoded.ID) > 0) {
+		encoded.NoData = true
+	} else {
+		sb.WriteString("data: " + prefixLines(data, "data: ") + "\n")
+	}
+	// the blank line dispatches the event, or just sets the last event ID if there is no data
+	sb.WriteString("\n")
+	encoded.Text = sb.String()
+	return encoded, true
+}
+
+// prefixLines starts every line of s after the first one with prefix,
+// CRLF and CR line endings are replaced by LF as clients accept all of them
+func prefixLines(s, prefix string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
 	}
-	return "data: " + data + "\n\n", true
+	return strings.NewReplacer("\r\n", "\n"+prefix, "\r", "\n"+prefix, "\n", "\n"+prefix).Replace(s)
 }
 
 func marshalText(text any) ([]byte, error) {
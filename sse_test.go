@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +27,7 @@ func TestSSEBroadcast(t *testing.T) {
 	ch <- 3
 	close(ch)
 
-	expected := "data: 1\n\ndata: 2\n\ndata: 3\n\n"
+	expected := "id: 1\ndata: 1\n\nid: 2\ndata: 2\n\nid: 3\ndata: 3\n\n"
 	if got := <-resp1; expected != got {
 		t.Errorf("expected <-resp1 == %q, got %q", expected, got)
 	}
@@ -48,7 +50,7 @@ func TestSSEBroadcastWithEventName(t *testing.T) {
 	ch <- 3
 	close(ch)
 
-	expected := "event: a\ndata: 1\n\nevent: a\ndata: 2\n\nevent: a\ndata: 3\n\n"
+	expected := "id: 1\nevent: a\ndata: 1\n\nid: 2\nevent: a\ndata: 2\n\nid: 3\nevent: a\ndata: 3\n\n"
 	if got := <-resp1; expected != got {
 		t.Errorf("expected <-resp1 == %q, got %q", expected, got)
 	}
@@ -68,7 +70,7 @@ func TestMultilineEvent(t *testing.T) {
 	ch <- "ab\ncd"
 	close(ch)
 
-	expected := "data: ab\ndata: cd\n\n"
+	expected := "id: 1\ndata: ab\ndata: cd\n\n"
 	if got := <-resp; expected != got {
 		t.Errorf("expected <-resp == %q, got %q", expected, got)
 	}
@@ -85,7 +87,7 @@ func TestTextEventSource(t *testing.T) {
 	ch <- "a b c d"
 	close(ch)
 
-	expected := "data: a b c d\n\n"
+	expected := "id: 1\ndata: a b c d\n\n"
 	if got := <-resp; expected != got {
 		t.Errorf("expected <-resp == %q, got %q", expected, got)
 	}
@@ -103,7 +105,7 @@ func TestTemplateEventSource(t *testing.T) {
 	ch <- "World"
 	close(ch)
 
-	expected := "data: <span>Hello World</span>\n\n"
+	expected := "id: 1\ndata: <span>Hello World</span>\n\n"
 	if got := <-resp; expected != got {
 		t.Errorf("expected <-resp == %q, got %q", expected, got)
 	}
@@ -125,15 +127,51 @@ func TestBundleEventSources(t *testing.T) {
 	close(ch1)
 	close(ch2)
 
-	expected := "event: event1\ndata: 1\n\nevent: event2\ndata: 2\n\n"
+	expected := "id: 1\nevent: event1\ndata: 1\n\nid: 2\nevent: event2\ndata: 2\n\n"
 	if got := <-resp; expected != got {
 		t.Errorf("expected <-resp == %q, got %q", expected, got)
 	}
 }
 
-func runRequest(h http.Handler, path string) <-chan string {
+func TestSSEKick(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""),
+		WithRequestListenerOptions(func(r *http.Request) []ListenerOption {
+			return []ListenerOption{WithListenerID(r.URL.Query().Get("id"))}
+		}))
+
+	resp1 := runRequest(b, "/?id=1")
+	resp2 := runRequest(b, "/?id=2")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- 1
+	time.Sleep(time.Millisecond)
+	if !b.Kick("1", nil) {
+		t.Error("listener should be kicked")
+	}
+	if infos := b.Listeners(); len(infos) != 1 || infos[0].ID != "2" {
+		t.Errorf("expected only listener 2, got %v", infos)
+	}
+	ch <- 2
+	close(ch)
+
+	expected1 := "id: 1\ndata: 1\n\n"
+	if got := <-resp1; expected1 != got {
+		t.Errorf("expected <-resp1 == %q, got %q", expected1, got)
+	}
+	expected2 := "id: 1\ndata: 1\n\nid: 2\ndata: 2\n\n"
+	if got := <-resp2; expected2 != got {
+		t.Errorf("expected <-resp2 == %q, got %q", expected2, got)
+	}
+}
+
+func runRequest(h http.Handler, path string, header ...string) <-chan string {
 	resp := make(chan string)
 	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
 	rec := httptest.NewRecorder()
 	go func() {
 		h.ServeHTTP(rec, req)
@@ -145,3 +183,300 @@ func runRequest(h http.Handler, path string) <-chan string {
 	}()
 	return resp
 }
+
+func TestSSEReplay(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""), WithReplay(1))
+
+	ch <- 1
+	ch <- 2
+	time.Sleep(time.Millisecond)
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- 3
+	close(ch)
+
+	expected := "id: 2\ndata: 2\n\nid: 3\ndata: 3\n\n"
+	if got := <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
+
+func TestSSELastEventID(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""), WithEventHistory(3))
+
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	time.Sleep(time.Millisecond)
+
+	resp1 := runRequest(b, "/", "Last-Event-ID", "1")
+	resp2 := runRequest(b, "/", "Last-Event-ID", "2")
+	resp3 := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- 4
+	close(ch)
+
+	expected1 := "id: 2\ndata: 2\n\nid: 3\ndata: 3\n\nid: 4\ndata: 4\n\n"
+	if got := <-resp1; expected1 != got {
+		t.Errorf("expected <-resp1 == %q, got %q", expected1, got)
+	}
+	expected2 := "id: 3\ndata: 3\n\nid: 4\ndata: 4\n\n"
+	if got := <-resp2; expected2 != got {
+		t.Errorf("expected <-resp2 == %q, got %q", expected2, got)
+	}
+	expected3 := "id: 4\ndata: 4\n\n"
+	if got := <-resp3; expected3 != got {
+		t.Errorf("expected <-resp3 == %q, got %q", expected3, got)
+	}
+}
+
+func TestSSELastEventIDCustom(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, "", WithEventID(func(val int) string {
+		return strconv.Itoa(val * 1000)
+	})), WithEventHistory(3))
+
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	time.Sleep(time.Millisecond)
+
+	resp1 := runRequest(b, "/", "Last-Event-ID", "1000")
+	resp2 := runRequest(b, "/", "Last-Event-ID", "9")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- 4
+	close(ch)
+
+	expected1 := "id: 2000\ndata: 2\n\nid: 3000\ndata: 3\n\nid: 4000\ndata: 4\n\n"
+	if got := <-resp1; expected1 != got {
+		t.Errorf("expected <-resp1 == %q, got %q", expected1, got)
+	}
+	// an unknown ID replays the whole history
+	expected2 := "id: 1000\ndata: 1\n\n" + expected1
+	if got := <-resp2; expected2 != got {
+		t.Errorf("expected <-resp2 == %q, got %q", expected2, got)
+	}
+}
+
+func TestSSELagged(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""),
+		WithListenerPolicy(DropNewest), WithListenerBufferSize(1))
+
+	gate := make(chan struct{})
+	rec := &gatedRecorder{ResponseRecorder: httptest.NewRecorder(), gate: gate}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	}()
+
+	time.Sleep(time.Millisecond)
+	ch <- 1 // blocks the writer
+	time.Sleep(time.Millisecond)
+	ch <- 2 // buffered
+	ch <- 3 // dropped
+	ch <- 4 // dropped
+	time.Sleep(time.Millisecond)
+	close(gate)
+	time.Sleep(time.Millisecond)
+	ch <- 5
+	close(ch)
+	<-done
+
+	expected := "id: 1\ndata: 1\n\nid: 2\ndata: 2\n\nevent: lagged\ndata: 2\n\nid: 5\ndata: 5\n\n"
+	if got := rec.Body.String(); expected != got {
+		t.Errorf("expected response %q, got %q", expected, got)
+	}
+}
+
+func TestSSEBatchedFlush(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""), WithListenerBufferSize(3))
+
+	gate := make(chan struct{})
+	rec := &gatedRecorder{ResponseRecorder: httptest.NewRecorder(), gate: gate}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	}()
+
+	time.Sleep(time.Millisecond)
+	ch <- 1 // blocks the writer
+	time.Sleep(time.Millisecond)
+	ch <- 2
+	ch <- 3
+	ch <- 4
+	time.Sleep(time.Millisecond)
+	close(gate)
+	close(ch)
+	<-done
+
+	expected := "id: 1\ndata: 1\n\nid: 2\ndata: 2\n\nid: 3\ndata: 3\n\nid: 4\ndata: 4\n\n"
+	if got := rec.Body.String(); expected != got {
+		t.Errorf("expected response %q, got %q", expected, got)
+	}
+	if rec.flushes != 1 {
+		t.Errorf("expected 1 flush, got %d", rec.flushes)
+	}
+}
+
+type gatedRecorder struct {
+	*httptest.ResponseRecorder
+	gate    <-chan struct{}
+	flushes int
+}
+
+func (r *gatedRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func (r *gatedRecorder) WriteString(s string) (int, error) {
+	<-r.gate
+	return r.ResponseRecorder.WriteString(s)
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""), WithHeartbeat(5*time.Millisecond))
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(20 * time.Millisecond)
+
+	ch <- 1
+	close(ch)
+
+	got := <-resp
+	if !strings.HasPrefix(got, ":\n\n") || !strings.HasSuffix(got, "\n\nid: 1\ndata: 1\n\n") {
+		t.Errorf("expected heartbeats before the event, got %q", got)
+	}
+}
+
+func TestSSEHeartbeatDeadClient(t *testing.T) {
+	ch := make(chan int)
+	defer close(ch)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""), WithHeartbeat(time.Millisecond))
+
+	rec := &failingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler didn't return after the write failed")
+	}
+	if n := len(b.Listeners()); n != 0 {
+		t.Errorf("expected 0 listeners, got %d", n)
+	}
+}
+
+type failingRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r *failingRecorder) WriteString(s string) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestSSEEventFields(t *testing.T) {
+	ch := make(chan Event)
+	b := NewSSEBroadcaster(ch)
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- SSEEvent{Name: "a", Data: "1", ID: "x", Retry: 3 * time.Second, Comment: "b\nc"}
+	ch <- SSEEvent{Data: "2"}
+	ch <- SSEEvent{Data: "3", ID: "bad\nid"}
+	close(ch)
+
+	expected := "id: x\nretry: 3000\n: b\n: c\nevent: a\ndata: 1\n\nid: 2\ndata: 2\n\nid: 3\ndata: 3\n\n"
+	if got := <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
+
+func TestSSEEventLineBreaks(t *testing.T) {
+	ch := make(chan Event)
+	b := NewSSEBroadcaster(ch)
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- SSEEvent{Comment: "x\rdata: injected", Data: "a\r\nb\rc"}
+	ch <- SSEEvent{Name: "n\rdata: injected", Data: "1"}
+	close(ch)
+
+	expected := "id: 1\n: x\n: data: injected\ndata: a\ndata: b\ndata: c\n\nid: 2\ndata: 1\n\n"
+	if got := <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
+
+func TestSSEEventWithoutData(t *testing.T) {
+	ch := make(chan Event)
+	b := NewSSEBroadcaster(ch)
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- SSEEvent{Comment: "x"}
+	ch <- SSEEvent{Retry: time.Second}
+	ch <- SSEEvent{ID: "y"}
+	ch <- SSEEvent{Data: "1"}
+	ch <- SSEEvent{}
+	time.Sleep(time.Millisecond)
+
+	// the ID-only event sets the resume point of the client
+	resumed := runRequest(b, "/", "Last-Event-ID", "y")
+	time.Sleep(time.Millisecond)
+	close(ch)
+
+	expected := ": x\n\nretry: 1000\n\nid: y\n\nid: 4\ndata: 1\n\nid: 5\ndata: \n\n"
+	if got := <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+	expected = "id: 4\ndata: 1\n\nid: 5\ndata: \n\n"
+	if got := <-resumed; expected != got {
+		t.Errorf("expected <-resumed == %q, got %q", expected, got)
+	}
+}
+
+func TestEventSourceID(t *testing.T) {
+	ch := make(chan int)
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, "", WithEventID(func(val int) string {
+		return "event-" + strconv.Itoa(val)
+	})))
+
+	resp := runRequest(b, "/")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- 1
+	ch <- 2
+	close(ch)
+
+	expected := "id: event-1\ndata: 1\n\nid: event-2\ndata: 2\n\n"
+	if got := <-resp; expected != got {
+		t.Errorf("expected <-resp == %q, got %q", expected, got)
+	}
+}
@@ -2,12 +2,15 @@ package broadcaster
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func ListenSSE(ctx context.Context, url string, opts ...SSEListenerOption) (<-chan Event, error) {
@@ -28,8 +31,12 @@ func ListenSSE(ctx context.Context, url string, opts ...SSEListenerOption) (<-ch
 
 		var builder sseEventBuilder
 
-		scanner := bufio.NewScanner(r)
-		scanner.Split(bufio.ScanLines)
+		br := bufio.NewReader(r)
+		if bom, _ := br.Peek(len(utf8BOM)); string(bom) == utf8BOM {
+			br.Discard(len(utf8BOM))
+		}
+		scanner := bufio.NewScanner(br)
+		scanner.Split(scanSSELines())
 		for scanner.Scan() {
 			line := scanner.Text()
 			if e := builder.addLine(line); e != nil {
@@ -44,57 +51,113 @@ func ListenSSE(ctx context.Context, url string, opts ...SSEListenerOption) (<-ch
 	return events, nil
 }
 
+const utf8BOM = "\ufeff"
+
+// scanSSELines splits the stream at CRLF, LF or CR line endings.
+// A CR at the end of the buffer ends the line right away, so the LF that may follow it is skipped later.
+func scanSSELines() bufio.SplitFunc {
+	var skipLF bool
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		var start int
+		if skipLF && len(data) > 0 {
+			skipLF = false
+			if data[0] == '\n' {
+				start = 1
+			}
+		}
+		if i := bytes.IndexAny(data[start:], "\r\n"); i >= 0 {
+			i += start
+			if data[i] == '\r' {
+				if i+1 == len(data) {
+					skipLF = true
+				} else if data[i+1] == '\n' {
+					return i + 2, data[start:i], nil
+				}
+			}
+			return i + 1, data[start:i], nil
+		}
+		if atEOF && len(data) > start {
+			return len(data), data[start:], nil
+		}
+		return start, nil, nil
+	}
+}
+
+// sseEventBuilder follows the event stream interpretation of the WHATWG HTML standard
 type sseEventBuilder struct {
-	name string
-	data string
+	name        string
+	data        strings.Builder
+	lastEventID string
+	retry       time.Duration
 }
 
 func (b *sseEventBuilder) addLine(line string) Event {
 	if len(line) == 0 {
-		if len(b.name) > 0 || len(b.data) > 0 {
-			e := &textEvent{name: b.name, data: b.data}
-			b.reset()
-			return e
-		}
-		return nil
+		return b.dispatch()
+	}
+	if line[0] == ':' {
+		return nil // comment
 	}
-	prefix, value, ok := strings.Cut(line, ": ")
-	if !ok {
-		return errorEvent("malformed line: " + line)
+	field, value, ok := strings.Cut(line, ":")
+	if ok {
+		value = strings.TrimPrefix(value, " ")
 	}
-	switch prefix {
+	switch field {
 	case "event":
-		if len(b.data) > 0 {
-			return errorEvent("event name sent after data: " + value)
-		}
 		b.name = value
-	case "", "id", "retry":
 	case "data":
-		if len(b.data) > 0 {
-			b.data += "\n" + value
-		} else {
-			b.data = value
+		b.data.WriteString(value)
+		b.data.WriteByte('\n')
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			b.lastEventID = value
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+			b.retry = time.Duration(ms) * time.Millisecond
 		}
-	default:
-		return errorEvent("malformed line: " + line)
 	}
 	return nil
 }
 
-func (b *sseEventBuilder) reset() {
+func (b *sseEventBuilder) dispatch() Event {
+	// events without data are not dispatched, the last event ID and retry are kept for the next one
+	if b.data.Len() == 0 {
+		b.name = ""
+		return nil
+	}
+	data := b.data.String()
+	e := &textEvent{
+		name:  b.name,
+		data:  data[:len(data)-1],
+		id:    b.lastEventID,
+		retry: b.retry,
+	}
 	b.name = ""
-	b.data = ""
+	b.data.Reset()
+	b.retry = 0
+	return e
 }
 
 type textEvent struct {
-	name string
-	data string
+	name  string
+	data  string
+	id    string
+	retry time.Duration
 }
 
 func (e textEvent) Read() (name, data string) {
 	return e.name, e.data
 }
 
+func (e textEvent) EventID() string {
+	return e.id
+}
+
+func (e textEvent) EventRetry() time.Duration {
+	return e.retry
+}
+
 func errorEvent(data string) Event {
 	return &textEvent{name: "error", data: data}
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/razzie/broadcaster"
 )
@@ -37,6 +39,81 @@ func TestListenSSE(t *testing.T) {
 	}
 }
 
+func TestListenSSEParser(t *testing.T) {
+	type event struct {
+		name, data, id string
+		retry          time.Duration
+	}
+	testCases := []struct {
+		name     string
+		resp     string
+		expected []event
+	}{
+		{"no space", "data:1\n\n", []event{{data: "1"}}},
+		{"extra space", "data:  1\n\n", []event{{data: " 1"}}},
+		{"field name only", "data\n\ndata\ndata\n\n", []event{{}, {data: "\n"}}},
+		{"comments", ":\n: ping\n:ping\ndata: 1\n\n", []event{{data: "1"}}},
+		{"CRLF", "event: a\r\ndata: 1\r\n\r\n", []event{{name: "a", data: "1"}}},
+		{"CR", "event: a\rdata: 1\rdata: 2\r\r", []event{{name: "a", data: "1\n2"}}},
+		{"BOM", "\ufeffdata: 1\n\n", []event{{data: "1"}}},
+		{"event after data", "data: 1\nevent: a\n\n", []event{{name: "a", data: "1"}}},
+		{"no data", "event: a\n\ndata: 1\n\n", []event{{data: "1"}}},
+		{"unknown field", "foo: bar\ndata: 1\n\n", []event{{data: "1"}}},
+		{"incomplete", "data: 1\n\ndata: 2\n", []event{{data: "1"}}},
+		{"id", "id: 1\ndata: a\n\ndata: b\n\nid\ndata: c\n\nid: x\x00\ndata: d\n\n",
+			[]event{{data: "a", id: "1"}, {data: "b", id: "1"}, {data: "c"}, {data: "d"}}},
+		{"retry", "retry: 1000\n\ndata: a\n\nretry: 1s\ndata: b\n\n",
+			[]event{{data: "a", retry: time.Second}, {data: "b"}}},
+	}
+	for _, tc := range testCases {
+		server := httptest.NewServer(dummySSEHandler(tc.resp))
+		events, err := ListenSSE(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("%s: unexpected error listening to server sent events: %v", tc.name, err)
+		}
+
+		var got []event
+		for e := range events {
+			var ge event
+			ge.name, ge.data = e.Read()
+			ge.id = e.(EventWithID).EventID()
+			ge.retry = e.(EventWithRetry).EventRetry()
+			got = append(got, ge)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, got)
+		}
+		server.Close()
+	}
+}
+
+func TestListenSSESplitCRLF(t *testing.T) {
+	chunks := []string{"data: 1\r", "\ndata: 2\r", "\n\r", "\n"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, chunk := range chunks {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	events, err := ListenSSE(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error listening to server sent events: %v", err)
+	}
+	var got []string
+	for e := range events {
+		_, data := e.Read()
+		got = append(got, data)
+	}
+	if expected := []string{"1\n2"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
 func dummySSEHandler(response string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "text/event-stream")
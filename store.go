@@ -0,0 +1,245 @@
+package broadcaster
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Store[T any] interface {
+	Append(e Envelope[T]) error
+	Scan(from uint64, fn func(Envelope[T]) bool) error
+	LastSeq() uint64
+	Close() error
+}
+
+type Unmarshaler func([]byte, any) error
+
+type storeOptions struct {
+	maxCount    int
+	maxAge      time.Duration
+	maxBytes    int64
+	segmentSize int64
+}
+
+type StoreOption func(*storeOptions)
+
+var defaultStoreOptions = storeOptions{
+	segmentSize: 16 << 20,
+}
+
+func WithRetentionCount(n int) StoreOption {
+	return func(so *storeOptions) {
+		so.maxCount = n
+	}
+}
+
+func WithRetentionAge(age time.Duration) StoreOption {
+	return func(so *storeOptions) {
+		so.maxAge = age
+	}
+}
+
+func WithRetentionBytes(n int64) StoreOption {
+	return func(so *storeOptions) {
+		so.maxBytes = n
+	}
+}
+
+func WithSegmentSize(n int64) StoreOption {
+	return func(so *storeOptions) {
+		so.segmentSize = n
+	}
+}
+
+type memoryStore[T any] struct {
+	storeOptions
+	mu      sync.RWMutex
+	entries []Envelope[T]
+	lastSeq uint64
+}
+
+func NewMemoryStore[T any](opts ...StoreOption) Store[T] {
+	s := &memoryStore[T]{
+		storeOptions: defaultStoreOptions,
+	}
+	for _, opt := range opts {
+		opt(&s.storeOptions)
+	}
+	return s
+}
+
+func (s *memoryStore[T]) Append(e Envelope[T]) error {
+	e.Missed = 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, e)
+	s.lastSeq = e.Seq
+
+	var expired int
+	if s.maxCount > 0 && len(s.entries) > s.maxCount {
+		expired = len(s.entries) - s.maxCount
+	}
+	if s.maxAge > 0 {
+		oldest := e.Time.Add(-s.maxAge)
+		for expired < len(s.entries) && s.entries[expired].Time.Before(oldest) {
+			expired++
+		}
+	}
+	s.entries = s.entries[expired:]
+	return nil
+}
+
+func (s *memoryStore[T]) Scan(from uint64, fn func(Envelope[T]) bool) error {
+	// appends don't modify the entries of the snapshot
+	s.mu.RLock()
+	entries := s.entries
+	s.mu.RUnlock()
+
+	i, _ := slices.BinarySearchFunc(entries, from, func(e Envelope[T], seq uint64) int {
+		return cmp.Compare(e.Seq, seq)
+	})
+	for _, e := range entries[i:] {
+		if !fn(e) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore[T]) seek(t time.Time) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return !s.entries[i].Time.Before(t)
+	})
+	if i == len(s.entries) {
+		return s.lastSeq + 1
+	}
+	return s.entries[i].Seq
+}
+
+func (s *memoryStore[T]) LastSeq() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSeq
+}
+
+func (s *memoryStore[T]) Close() error {
+	return nil
+}
+
+// listeners that fall further behind while catching up continue with the live objects
+const maxCatchUpPending = 1024
+
+// seeker is implemented by stores that can find the first sequence number stored at or after t
+type seeker interface {
+	seek(t time.Time) uint64
+}
+
+type catchUp[T any] struct {
+	until   uint64
+	scanned uint64 // the last stored object that was sent or skipped
+	pending []Envelope[T]
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func (b *broadcaster[In, Out]) startCatchUp(l *listener[Out]) {
+	c := &catchUp[Out]{
+		until: b.seq,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	l.catchUp = c
+
+	from := max(l.startSeq, 1)
+	if s, ok := b.store.(seeker); ok && !l.startTime.IsZero() {
+		from = max(from, s.seek(l.startTime))
+	}
+	c.scanned = from - 1
+
+	go func() {
+		if l.resumeAfter != nil {
+			b.store.Scan(from, func(e Envelope[Out]) bool {
+				if e.Seq > c.until {
+					return false
+				}
+				if l.resumeAfter(e) {
+					from = e.Seq + 1
+					c.scanned = e.Seq
+					return false
+				}
+				return true
+			})
+		}
+		b.store.Scan(from, func(e Envelope[Out]) bool {
+			if e.Seq > c.until {
+				return false
+			}
+			skip := e.Time.Before(l.startTime) || !l.accepts(e.Value)
+			if skip || l.out.send(e, nil, c.stop, l.cancelled) {
+				c.scanned = e.Seq
+				return true
+			}
+			return false
+		})
+		close(c.done)
+
+		select {
+		case b.caughtUp <- l:
+		case <-b.closed:
+		}
+	}()
+}
+
+// finishCatchUp sends the objects that were broadcast while the listener was catching up
+func (b *broadcaster[In, Out]) finishCatchUp(l *listener[Out]) {
+	c := l.catchUp
+	if _, ok := b.listeners[l]; !ok || c == nil {
+		return
+	}
+	l.catchUp = nil
+	for _, e := range c.pending {
+		b.deliver(l, e)
+		if len(b.slow) > 0 {
+			b.broadcastSlow(e)
+		}
+	}
+}
+
+// closeAfterCatchUp lets the listener receive the rest of its objects before it is closed,
+// so a listener that joined right before the broadcaster was closed doesn't miss any
+func (l *listener[T]) closeAfterCatchUp(err error) {
+	c := l.catchUp
+	l.catchUp = nil
+	go func() {
+		<-c.done
+		for _, e := range c.pending {
+			if !l.out.send(e, nil, nil, l.cancelled) {
+				break
+			}
+		}
+		l.close(err)
+	}()
+}
+
+// abortCatchUp makes a listener that fell too far behind continue with the live objects,
+// the skipped ones are reported as missed
+func (l *listener[T]) abortCatchUp() {
+	c := l.catchUp
+	l.stopCatchUp()
+	l.missed += len(c.pending) + int(c.until-min(c.scanned, c.until))
+}
+
+func (l *listener[T]) stopCatchUp() {
+	if c := l.catchUp; c != nil {
+		close(c.stop)
+		<-c.done
+		l.catchUp = nil
+	}
+}
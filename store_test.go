@@ -0,0 +1,154 @@
+package broadcaster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func scanValues[T any](t *testing.T, s Store[T], from uint64) []T {
+	t.Helper()
+	var values []T
+	if err := s.Scan(from, func(e Envelope[T]) bool {
+		values = append(values, e.Value)
+		return true
+	}); err != nil {
+		t.Fatalf("unexpected scan error: %v", err)
+	}
+	return values
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore[int](WithRetentionCount(3))
+
+	now := time.Now()
+	for i := 1; i <= 5; i++ {
+		if err := s.Append(Envelope[int]{Seq: uint64(i), Time: now, Value: i}); err != nil {
+			t.Fatalf("unexpected append error: %v", err)
+		}
+	}
+
+	if seq := s.LastSeq(); seq != 5 {
+		t.Errorf("expected last seq 5, got %d", seq)
+	}
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []int{3, 4, 5}) {
+		t.Errorf("expected [3 4 5], got %v", values)
+	}
+	if values := scanValues(t, s, 4); !reflect.DeepEqual(values, []int{4, 5}) {
+		t.Errorf("expected [4 5], got %v", values)
+	}
+}
+
+func TestMemoryStoreRetentionAge(t *testing.T) {
+	s := NewMemoryStore[int](WithRetentionAge(time.Minute))
+
+	now := time.Now()
+	s.Append(Envelope[int]{Seq: 1, Time: now.Add(-2 * time.Minute), Value: 1})
+	s.Append(Envelope[int]{Seq: 2, Time: now.Add(-30 * time.Second), Value: 2})
+	s.Append(Envelope[int]{Seq: 3, Time: now, Value: 3})
+
+	if values := scanValues(t, s, 0); !reflect.DeepEqual(values, []int{2, 3}) {
+		t.Errorf("expected [2 3], got %v", values)
+	}
+}
+
+func TestStoreCatchUp(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithStore(NewMemoryStore[int]()))
+
+	for i := 1; i <= 3; i++ {
+		ch <- i
+	}
+
+	l, _, err := b.ListenEnvelopes(WithStartSeq(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	ch <- 4
+
+	var got []uint64
+	for e := range l {
+		got = append(got, e.Seq)
+		if e.Seq == 4 {
+			break
+		}
+	}
+	close(ch)
+	if !reflect.DeepEqual(got, []uint64{2, 3, 4}) {
+		t.Errorf("expected seqs [2 3 4], got %v", got)
+	}
+}
+
+func TestStoreTypeMismatch(t *testing.T) {
+	ch := make(chan int)
+	b := NewBroadcaster(ch, WithStore(NewMemoryStore[string]()))
+
+	<-b.Done()
+	if _, err := b.ListenHandle(); err != ErrOptionType {
+		t.Errorf("expected err == ErrOptionType, got '%v'", err)
+	}
+}
+
+func TestStoreCatchUpOverflow(t *testing.T) {
+	ch := make(chan int)
+	defer close(ch)
+	b := NewBroadcaster(ch, WithStore(NewMemoryStore[int]()))
+
+	for i := 1; i <= 3; i++ {
+		ch <- i
+	}
+
+	l, _, err := b.ListenEnvelopes(WithStartSeq(1), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	if e := <-l; e.Seq != 1 {
+		t.Errorf("expected seq 1, got %d", e.Seq)
+	}
+	time.Sleep(time.Millisecond)
+
+	// 2 is buffered and the catch-up is stuck at 3 while the live objects pile up
+	const last = 3 + 1024 + 1
+	for i := 4; i <= last; i++ {
+		ch <- i
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if e := <-l; e.Seq != 2 {
+		t.Errorf("expected seq 2, got %d", e.Seq)
+	}
+	if e := <-l; e.Seq != last || e.Missed != last-3 {
+		t.Errorf("expected seq %d with %d missed, got %d with %d missed", last, last-3, e.Seq, e.Missed)
+	}
+}
+
+func TestStoreStartTime(t *testing.T) {
+	s, err := NewFileStore[int](t.TempDir(), nil, nil, WithSegmentSize(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		s.Append(Envelope[int]{Seq: uint64(i), Time: start.Add(time.Duration(i) * time.Minute), Value: i})
+	}
+
+	ch := make(chan int)
+	defer close(ch)
+	b := NewBroadcaster(ch, WithStore(s))
+
+	l, _, err := b.Listen(WithStartTime(start.Add(3 * time.Minute)))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+	ch <- 6
+
+	for _, expected := range []int{3, 4, 5, 6} {
+		if val := <-l; val != expected {
+			t.Errorf("expected <-l == %d, got %d", expected, val)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package broadcaster
+
+import (
+	"context"
+	"sync"
+)
+
+type Subject[T any] interface {
+	Broadcaster[T]
+	Publish(ctx context.Context, val T) error
+	PublishTo(ctx context.Context, target Target, val T) error
+	TryPublish(val T) bool
+	Close()
+}
+
+type subject[T any] struct {
+	Broadcaster[T]
+	mu      sync.RWMutex
+	input   chan Targeted[T]
+	closing chan struct{}
+	close   func()
+}
+
+func NewSubject[T any](opts ...BroadcasterOption) Subject[T] {
+	input := make(chan Targeted[T])
+	s := &subject[T]{
+		Broadcaster: NewTargetedBroadcaster(input, opts...),
+		input:       input,
+		closing:     make(chan struct{}),
+	}
+	s.close = sync.OnceFunc(func() {
+		close(s.closing)
+		s.mu.Lock()
+		close(s.input)
+		s.mu.Unlock()
+	})
+	return s
+}
+
+func (s *subject[T]) Publish(ctx context.Context, val T) error {
+	return s.PublishTo(ctx, Target{}, val)
+}
+
+func (s *subject[T]) PublishTo(ctx context.Context, target Target, val T) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-s.closing:
+		return ErrBroadcasterClosed
+	default:
+	}
+
+	select {
+	case <-s.closing:
+		return ErrBroadcasterClosed
+	case <-s.Done():
+		return ErrBroadcasterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.input <- Targeted[T]{Target: target, Value: val}:
+		return nil
+	}
+}
+
+func (s *subject[T]) TryPublish(val T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-s.closing:
+		return false
+	default:
+	}
+
+	select {
+	case s.input <- Targeted[T]{Value: val}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *subject[T]) Close() {
+	s.close()
+	<-s.Done()
+}
@@ -0,0 +1,99 @@
+package broadcaster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestSubject(t *testing.T) {
+	s := NewSubject[int]()
+
+	l, _, err := s.Listen(WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	if err := s.Publish(context.Background(), 1); err != nil {
+		t.Errorf("unexpected publish error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if !s.TryPublish(2) {
+		t.Error("expected TryPublish to succeed")
+	}
+
+	for _, expected := range []int{1, 2} {
+		if val := <-l; val != expected {
+			t.Errorf("expected <-l == %d, but got %d", expected, val)
+		}
+	}
+}
+
+func TestSubjectClose(t *testing.T) {
+	s := NewSubject[int]()
+
+	l, _, err := s.Listen()
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	s.Close()
+	s.Close()
+
+	if _, ok := <-l; ok {
+		t.Error("expected l to be closed")
+	}
+	if !s.IsClosed() {
+		t.Error("subject should be closed")
+	}
+	if err := s.Publish(context.Background(), 1); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+	if s.TryPublish(1) {
+		t.Error("expected TryPublish to fail")
+	}
+	if _, _, err := s.Listen(); err != ErrBroadcasterClosed {
+		t.Errorf("expected err == ErrBroadcasterClosed, got '%v'", err)
+	}
+}
+
+func TestSubjectPublishContext(t *testing.T) {
+	s := NewSubject[int](WithBlocking(true))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := s.Publish(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("expected err == context.DeadlineExceeded, got '%v'", err)
+	}
+}
+
+func TestSubjectPublishTo(t *testing.T) {
+	s := NewSubject[int]()
+
+	l1, _, _ := s.Listen(WithListenerID("1"), WithBufferSize(2))
+	l2, _, _ := s.Listen(WithListenerID("2"), WithBufferSize(2))
+
+	s.PublishTo(context.Background(), Target{ID: "1"}, 1)
+	s.Publish(context.Background(), 2)
+	s.Close()
+
+	for _, tc := range []struct {
+		l        <-chan int
+		expected []int
+	}{
+		{l1, []int{1, 2}},
+		{l2, []int{2}},
+	} {
+		for _, expected := range tc.expected {
+			if val := <-tc.l; val != expected {
+				t.Errorf("expected <-l == %d, but got %d", expected, val)
+			}
+		}
+		if _, ok := <-tc.l; ok {
+			t.Error("expected l to be closed")
+		}
+	}
+}
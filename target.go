@@ -0,0 +1,50 @@
+package broadcaster
+
+type Target struct {
+	ID     string
+	Labels map[string]string
+}
+
+type Targeted[T any] struct {
+	Target Target
+	Value  T
+}
+
+func NewTargetedBroadcaster[T any](input <-chan Targeted[T], opts ...BroadcasterOption) Broadcaster[T] {
+	opts = append([]BroadcasterOption{withRoute(targetOf[T])}, opts...)
+	return NewConverterBroadcaster(input, valueOf[T], opts...)
+}
+
+func (t Target) isZero() bool {
+	return len(t.ID) == 0 && len(t.Labels) == 0
+}
+
+func (t Target) matches(id string, labels map[string]string) bool {
+	if len(t.ID) > 0 && t.ID != id {
+		return false
+	}
+	for key, value := range t.Labels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (t Targeted[T]) route() (Target, any) {
+	return t.Target, t.Value
+}
+
+func withRoute[In any](route func(In) Target) BroadcasterOption {
+	return func(bo *broadcasterOptions) {
+		bo.route = route
+	}
+}
+
+func targetOf[T any](t Targeted[T]) Target {
+	return t.Target
+}
+
+func valueOf[T any](t Targeted[T]) (T, bool) {
+	return t.Value, true
+}
@@ -0,0 +1,70 @@
+package broadcaster_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/razzie/broadcaster"
+)
+
+func TestTargetedBroadcaster(t *testing.T) {
+	ch := make(chan Targeted[int])
+	b := NewTargetedBroadcaster(ch, WithReplay(3), WithListenerBufferSize(3))
+
+	admin, _, _ := b.Listen(WithListenerID("a"), WithLabels(map[string]string{"role": "admin"}))
+	user, _, _ := b.Listen(WithListenerID("b"), WithLabels(map[string]string{"role": "user"}))
+	anon, _, _ := b.Listen()
+
+	ch <- Targeted[int]{Value: 1}
+	ch <- Targeted[int]{Target: Target{ID: "b"}, Value: 2}
+	ch <- Targeted[int]{Target: Target{Labels: map[string]string{"role": "admin"}}, Value: 3}
+	late, _, _ := b.Listen(WithLabels(map[string]string{"role": "admin"}))
+	close(ch)
+
+	for _, tc := range []struct {
+		name     string
+		l        <-chan int
+		expected []int
+	}{
+		{"admin", admin, []int{1, 3}},
+		{"user", user, []int{1, 2}},
+		{"anon", anon, []int{1}},
+		{"late", late, []int{1}},
+	} {
+		var got []int
+		for val := range tc.l {
+			got = append(got, val)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestSSETargeted(t *testing.T) {
+	ch := make(chan Targeted[int])
+	b := NewSSEBroadcaster(NewJsonEventSource(ch, ""),
+		WithRequestListenerOptions(func(r *http.Request) []ListenerOption {
+			return []ListenerOption{WithListenerID(r.URL.Query().Get("id"))}
+		}))
+
+	resp1 := runRequest(b, "/?id=1")
+	resp2 := runRequest(b, "/?id=2")
+
+	time.Sleep(time.Millisecond)
+
+	ch <- Targeted[int]{Target: Target{ID: "2"}, Value: 1}
+	ch <- Targeted[int]{Value: 2}
+	close(ch)
+
+	expected1 := "id: 2\ndata: 2\n\n"
+	if got := <-resp1; expected1 != got {
+		t.Errorf("expected <-resp1 == %q, got %q", expected1, got)
+	}
+	expected2 := "id: 1\ndata: 1\n\nid: 2\ndata: 2\n\n"
+	if got := <-resp2; expected2 != got {
+		t.Errorf("expected <-resp2 == %q, got %q", expected2, got)
+	}
+}